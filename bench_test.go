@@ -4,6 +4,8 @@ package errors
 
 import (
 	"fmt"
+	"runtime"
+	"strings"
 	"testing"
 
 	stderrors "errors"
@@ -61,3 +63,92 @@ func BenchmarkErrors(b *testing.B) {
 		})
 	}
 }
+
+func wrapErrors(at, depth int, policy StackPolicy) error {
+	if at >= depth {
+		return policy.New("ye error")
+	}
+	return policy.Wrap(wrapErrors(at+1, depth, policy), "wrapped")
+}
+
+func BenchmarkStackPolicy(b *testing.B) {
+	policies := []struct {
+		name   string
+		policy StackPolicy
+	}{
+		{"always", StackPolicy{Mode: StackAlways}},
+		{"always-depth-8", StackPolicy{Mode: StackAlways, MaxDepth: 8}},
+		{"always-depth-128", StackPolicy{Mode: StackAlways, MaxDepth: 128}},
+		{"never", StackPolicy{Mode: StackNever}},
+		{"firstwrap", StackPolicy{Mode: StackFirstWrap}},
+		{"sample-10", StackPolicy{Mode: StackAlways, SampleEvery: 10}},
+	}
+	for _, p := range policies {
+		for _, wraps := range []int{1, 10} {
+			name := fmt.Sprintf("%s-wraps-%d", p.name, wraps)
+			b.Run(name, func(b *testing.B) {
+				var err error
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					err = wrapErrors(1, wraps, p.policy)
+				}
+				b.StopTimer()
+				GlobalE = err
+			})
+		}
+	}
+}
+
+// formatUncached formats the frames of st like %+v
+// by resolving every program counter again.
+func formatUncached(st StackTrace) string {
+	var b strings.Builder
+	for _, f := range st {
+		fn := runtime.FuncForPC(uintptr(f) - 1)
+		file, line := fn.FileLine(uintptr(f) - 1)
+		fmt.Fprintf(&b, "\n%s\n\t%s:%d", fn.Name(), file, line)
+	}
+	return b.String()
+}
+
+// GlobalS is an exported global to store the result of benchmark results,
+// preventing the compiler from optimising the benchmark functions away.
+var GlobalS string
+
+func BenchmarkFormatStack(b *testing.B) {
+	for _, depth := range []int{10, 30} {
+		err := yesErrors(0, depth)
+		st := err.(interface{ StackTrace() StackTrace }).StackTrace()
+
+		b.Run(fmt.Sprintf("uncached-stack-%d", depth), func(b *testing.B) {
+			var s string
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s = formatUncached(st)
+			}
+			b.StopTimer()
+			GlobalS = s
+		})
+		b.Run(fmt.Sprintf("same-error-stack-%d", depth), func(b *testing.B) {
+			var s string
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s = fmt.Sprintf("%+v", err)
+			}
+			b.StopTimer()
+			GlobalS = s
+		})
+		b.Run(fmt.Sprintf("new-error-stack-%d", depth), func(b *testing.B) {
+			var s string
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				err := yesErrors(0, depth)
+				b.StartTimer()
+				s = fmt.Sprintf("%+v", err)
+			}
+			b.StopTimer()
+			GlobalS = s
+		})
+	}
+}
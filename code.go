@@ -0,0 +1,241 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// WithCode annotates err with a classification code
+// of a user defined comparable type, for example
+// an enum type used to map errors to HTTP statuses.
+// The Error method of the returned error returns err.Error() unchanged,
+// the code can be retrieved with the Code function
+// through any wrapping with Wrap, WithMessage, or Combine.
+// If err is nil, WithCode returns nil.
+// Codes should be comparable, codes of other types
+// are compared with reflect.DeepEqual by HasCode.
+// To keep the type of codes in errors reconstructed
+// by FromJSON, the codes have to be registered with RegisterCodes.
+// Example:
+//     type ErrorCode int
+//
+//     const (
+//         CodeNotFound ErrorCode = iota + 1
+//         CodeInvalidInput
+//     )
+//
+//     return errors.WithCode(err, CodeNotFound)
+func WithCode(err error, code interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return &withCode{
+		cause: err,
+		code:  code,
+	}
+}
+
+type withCode struct {
+	cause error
+	code  interface{}
+
+	// codeType is the type name of a not registered code
+	// decoded by FromJSON to keep it for serialization
+	codeType string
+}
+
+func (w *withCode) Error() string {
+	return w.cause.Error()
+}
+
+func (w *withCode) Cause() error {
+	return w.cause
+}
+
+func (w *withCode) Unwrap() error {
+	return w.cause
+}
+
+func (w *withCode) Code() interface{} {
+	return w.code
+}
+
+func (w *withCode) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%+v\ncode=%v", w.Cause(), w.code)
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, w.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", w.Error())
+	}
+}
+
+// Code returns the outermost code of err and the errors
+// in its tree of causes and combined errors, which is
+// the code with the least wrapping depth as traversed by Walk,
+// or nil if there is no code.
+// The outermost code is the one that was attached last
+// and thus is usually the most specific classification.
+// Use InnermostCode to get the code closest to the root cause.
+//
+// Codes are attached with WithCode or by errors
+// that implement the following interface:
+//
+//     type coder interface {
+//             Code() interface{}
+//     }
+//
+// so that coded sentinel errors can be declared as constants:
+//
+//     type NotFoundError string
+//
+//     func (e NotFoundError) Error() string   { return string(e) }
+//     func (NotFoundError) Code() interface{} { return CodeNotFound }
+//
+//     const ErrDocumentNotFound NotFoundError = "document not found"
+func Code(err error) interface{} {
+	return findCode(err, false)
+}
+
+// InnermostCode returns the innermost code of err and the errors
+// in its tree of causes and combined errors, which is
+// the code with the greatest wrapping depth as traversed by Walk,
+// or nil if there is no code.
+// See Code for details.
+func InnermostCode(err error) interface{} {
+	return findCode(err, true)
+}
+
+// HasCode returns if code was attached to err
+// or any error in its tree of causes and combined errors.
+func HasCode(err error, code interface{}) bool {
+	type coder interface {
+		Code() interface{}
+	}
+
+	found := false
+	Walk(err, func(e error, depth int) bool {
+		if c, ok := e.(coder); ok && equalCodes(c.Code(), code) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// equalCodes returns if a and b have the same type
+// and are equal compared with == if their type is comparable,
+// or with reflect.DeepEqual if not.
+// It returns false instead of panicking for comparable types
+// holding not comparable values in interface fields.
+func equalCodes(a, b interface{}) (equal bool) {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) {
+		return false
+	}
+	if !t.Comparable() {
+		return reflect.DeepEqual(a, b)
+	}
+	defer func() {
+		if recover() != nil {
+			equal = false
+		}
+	}()
+	return a == b
+}
+
+var (
+	registeredCodes    = make(map[codeKey]interface{})
+	registeredCodesMtx sync.RWMutex
+)
+
+type codeKey struct {
+	typ  string
+	json string
+}
+
+func newCodeKey(typ string, code interface{}) (codeKey, bool) {
+	data, err := json.Marshal(code)
+	if err != nil {
+		return codeKey{}, false
+	}
+	return codeKey{typ, string(data)}, true
+}
+
+// RegisterCodes registers codes so that errors reconstructed by FromJSON
+// return the registered values from Code instead of the values
+// decoded from JSON, which are float64 for numeric codes.
+// A serialized code is matched by its type name and JSON representation.
+// Example:
+//     errors.RegisterCodes(CodeNotFound, CodeInvalidInput)
+func RegisterCodes(codes ...interface{}) {
+	registeredCodesMtx.Lock()
+	defer registeredCodesMtx.Unlock()
+
+	for _, code := range codes {
+		if key, ok := newCodeKey(fmt.Sprintf("%T", code), code); ok && code != nil {
+			registeredCodes[key] = code
+		}
+	}
+}
+
+// UnregisterCodes removes codes registered with RegisterCodes.
+func UnregisterCodes(codes ...interface{}) {
+	registeredCodesMtx.Lock()
+	defer registeredCodesMtx.Unlock()
+
+	for _, code := range codes {
+		if key, ok := newCodeKey(fmt.Sprintf("%T", code), code); ok {
+			delete(registeredCodes, key)
+		}
+	}
+}
+
+// registeredCode returns the registered code with the type name typ
+// and the JSON representation of code.
+func registeredCode(typ string, code interface{}) (registered interface{}, ok bool) {
+	key, ok := newCodeKey(typ, code)
+	if !ok {
+		return nil, false
+	}
+
+	registeredCodesMtx.RLock()
+	defer registeredCodesMtx.RUnlock()
+
+	registered, ok = registeredCodes[key]
+	return registered, ok
+}
+
+// findCode returns the code with the least depth
+// or with the greatest depth if innermost is true.
+// Of codes with the same depth the first one is returned.
+func findCode(err error, innermost bool) (code interface{}) {
+	type coder interface {
+		Code() interface{}
+	}
+
+	codeDepth := -1
+	Walk(err, func(e error, depth int) bool {
+		c, ok := e.(coder)
+		if !ok || c.Code() == nil {
+			return true
+		}
+		if codeDepth == -1 || innermost && depth > codeDepth || !innermost && depth < codeDepth {
+			code, codeDepth = c.Code(), depth
+		}
+		// No deeper code can be more outer
+		return innermost
+	})
+	return code
+}
@@ -0,0 +1,115 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCode int
+
+const (
+	codeNotFound testCode = iota + 1
+	codeInvalid
+	codeInternal
+)
+
+type notFoundError string
+
+func (e notFoundError) Error() string   { return string(e) }
+func (notFoundError) Code() interface{} { return codeNotFound }
+
+const errDocumentNotFound notFoundError = "document not found"
+
+func Test_WithCode(t *testing.T) {
+	assert.NoError(t, WithCode(nil, codeNotFound))
+	assert.Nil(t, Code(nil))
+	assert.Nil(t, Code(io.EOF))
+
+	err := WithCode(io.EOF, codeNotFound)
+	assert.EqualError(t, err, "EOF")
+	assert.Equal(t, io.EOF, Cause(err))
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, codeNotFound, Code(err))
+	assert.Equal(t, codeNotFound, Code(Wrap(err, "wrapped")))
+	assert.Equal(t, codeNotFound, Code(fmt.Errorf("wrapped: %w", err)))
+
+	// Not comparable codes are compared deeply
+	err = WithCode(io.EOF, []int{1})
+	assert.True(t, HasCode(err, []int{1}))
+	assert.False(t, HasCode(err, []int{2}))
+
+	// Comparable codes holding not comparable values don't panic
+	type code struct{ value interface{} }
+	err = WithCode(io.EOF, code{[]int{1}})
+	assert.False(t, HasCode(err, code{[]int{1}}))
+	assert.True(t, HasCode(WithCode(io.EOF, code{1}), code{1}))
+	assert.False(t, HasCode(WithCode(io.EOF, codeInvalid), int(codeInvalid)))
+}
+
+func Test_CodePrecedence(t *testing.T) {
+	inner := WithCode(io.EOF, codeInvalid)
+	outer := WithCode(Wrap(inner, "wrapped"), codeInternal)
+	assert.Equal(t, codeInternal, Code(outer))
+	assert.Equal(t, codeInvalid, InnermostCode(outer))
+
+	// Codes of coded constants and combinations
+	err := Combine(
+		Wrap(WithMessage(WithCode(New("deep"), codeInvalid), "deep"), "first"),
+		Wrap(errDocumentNotFound, "second"),
+	)
+	assert.Equal(t, codeNotFound, Code(err))
+	assert.Equal(t, codeInvalid, InnermostCode(err))
+	assert.True(t, HasCode(err, codeInvalid))
+	assert.True(t, HasCode(err, codeNotFound))
+	assert.False(t, HasCode(err, codeInternal))
+
+	// Of codes with the same depth the first one is returned
+	err = Combine(WithCode(io.EOF, codeInternal), WithCode(io.EOF, codeInvalid))
+	assert.Equal(t, codeInternal, Code(err))
+	assert.Equal(t, codeInternal, InnermostCode(err))
+}
+
+func TestFormatWithCode(t *testing.T) {
+	err := WithCode(Const("error"), codeNotFound)
+	assert.Equal(t, "error", fmt.Sprintf("%v", err))
+	assert.Equal(t, `"error"`, fmt.Sprintf("%q", err))
+	assert.Equal(t, "error\ncode=1", fmt.Sprintf("%+v", err))
+}
+
+func Test_CodeJSON(t *testing.T) {
+	err := WithCode(io.EOF, codeNotFound)
+	data, e := json.Marshal(err)
+	assert.NoError(t, e)
+	assert.JSONEq(t, `{"type":"*errors.withCode","error":"EOF","code":1,"codeType":"errors.testCode","cause":{"type":"*errors.errorString","error":"EOF"}}`, string(data))
+
+	// Not registered codes are reconstructed with their JSON types
+	remote, e := FromJSON(data)
+	assert.NoError(t, e)
+	assert.EqualError(t, remote, "EOF")
+	assert.Equal(t, float64(1), Code(remote))
+	remoteData, e := MarshalChain(remote)
+	assert.NoError(t, e)
+	assert.Equal(t, string(data), string(remoteData))
+
+	RegisterCodes(codeNotFound, codeInvalid)
+	defer UnregisterCodes(codeNotFound, codeInvalid)
+	remote, e = FromJSON(data)
+	assert.NoError(t, e)
+	assert.Equal(t, codeNotFound, Code(remote))
+	assert.True(t, errors.Is(remote, io.EOF))
+
+	// Through wrapping and round trips
+	data, e = MarshalChain(Wrap(WithCode(New("e"), codeInvalid), "wrapped"))
+	assert.NoError(t, e)
+	remote, e = FromJSON(data)
+	assert.NoError(t, e)
+	assert.Equal(t, codeInvalid, Code(remote))
+	remoteData, e = MarshalChain(remote)
+	assert.NoError(t, e)
+	assert.Equal(t, string(data), string(remoteData))
+}
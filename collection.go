@@ -1,16 +1,45 @@
 package errors
 
-import "sync"
+import (
+	"errors"
+	"sync"
+)
 
 // Collection manages non nil errors with threadsafe methods.
+//
+// Duplicate errors are counted instead of being added
+// if Dedup is set, so that errors from many goroutines
+// hitting the same failure don't grow the Collection.
+// The number of errors can be limited with MaxErrors.
+// The configuration fields must not be changed
+// after the first error was added.
 type Collection struct {
-	errs []error
-	mtx  sync.RWMutex
+	// Dedup selects which added errors are duplicates
+	// of already added errors.
+	Dedup DedupMode
+
+	// KeepDuplicates adds duplicate errors
+	// and only counts them in the message
+	// of the combination returned by Combine.
+	KeepDuplicates bool
+
+	// MaxErrors limits the number of errors in the Collection
+	// if greater than zero. Errors added after the limit was
+	// reached are only counted as overflow, which is reported
+	// like "and 5 more" by the combination returned by Combine.
+	MaxErrors int
+
+	errs     []error
+	counts   []int          // nil if no duplicates were counted
+	messages map[string]int // indices of errs by message for DedupMessage, built lazily
+	overflow int
+	mtx      sync.RWMutex
 }
 
 // NewCollection returns a new Collection with initialErrors.
 func NewCollection(initialErrors ...error) *Collection {
-	return &Collection{errs: flatten(initialErrors)}
+	errs, counts, dropped := flattenCounted(initialErrors)
+	return &Collection{errs: errs, counts: counts, overflow: dropped}
 }
 
 // Add an error, but only if it is not nil.
@@ -22,42 +51,214 @@ func (c *Collection) Add(err error) {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
 
+	if !c.KeepDuplicates {
+		if i := c.index(err); i != -1 {
+			if c.counts == nil {
+				c.counts = make([]int, len(c.errs))
+				for j := range c.counts {
+					c.counts[j] = 1
+				}
+			}
+			c.counts[i]++
+			return
+		}
+	}
+	if c.MaxErrors > 0 && len(c.errs) >= c.MaxErrors {
+		c.overflow++
+		return
+	}
+	if c.messages != nil {
+		c.messages[err.Error()] = len(c.errs)
+	}
 	c.errs = append(c.errs, err)
+	if c.counts != nil {
+		c.counts = append(c.counts, 1)
+	}
+}
+
+// index returns the index of the first error
+// that err is a duplicate of according to c.Dedup,
+// or -1 if there is none.
+// Messages are looked up in a map for DedupMessage
+// to avoid calling the Error method of all errors for every added error.
+func (c *Collection) index(err error) int {
+	if c.Dedup != DedupMessage {
+		return c.Dedup.index(err, c.errs)
+	}
+	if c.messages == nil {
+		c.messages = make(map[string]int, len(c.errs))
+		for i, e := range c.errs {
+			if _, ok := c.messages[e.Error()]; !ok {
+				c.messages[e.Error()] = i
+			}
+		}
+	}
+	if i, ok := c.messages[err.Error()]; ok {
+		return i
+	}
+	return -1
 }
 
 // Remove removes all instances of err from the collection.
 func (c *Collection) Remove(err error) {
+	c.RemoveFunc(func(e error) bool { return e == err })
+}
+
+// RemoveIs removes all errors from the collection
+// for which errors.Is returns true for target,
+// like errors wrapping the sentinel error target.
+func (c *Collection) RemoveIs(target error) {
+	c.RemoveFunc(func(e error) bool { return errors.Is(e, target) })
+}
+
+// RemoveFunc removes all errors from the collection
+// for which remove returns true.
+func (c *Collection) RemoveFunc(remove func(error) bool) {
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
 
+	// Indices change, rebuild on next Add
+	c.messages = nil
 	for i := len(c.errs) - 1; i >= 0; i-- {
-		if c.errs[i] == err {
+		if remove(c.errs[i]) {
 			copy(c.errs[i:], c.errs[i+1:])
 			c.errs = c.errs[:len(c.errs)-1]
+			if c.counts != nil {
+				copy(c.counts[i:], c.counts[i+1:])
+				c.counts = c.counts[:len(c.counts)-1]
+			}
 		}
 	}
 }
 
-// Errors returns the errors in the collection.
+// Filter returns the errors in the collection
+// for which match returns true
+// without changing the collection.
+func (c *Collection) Filter(match func(error) bool) []error {
+	matching, _ := c.Partition(match)
+	return matching
+}
+
+// Partition returns the errors in the collection
+// for which match returns true as matching
+// and the other errors as others
+// without changing the collection.
+func (c *Collection) Partition(match func(error) bool) (matching, others []error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	for _, err := range c.errs {
+		if match(err) {
+			matching = append(matching, err)
+		} else {
+			others = append(others, err)
+		}
+	}
+	return matching, others
+}
+
+// Errors returns a copy of the errors in the collection.
+// Duplicates are not included if they were counted
+// because of the Dedup mode of the Collection,
+// and neither are errors that exceeded MaxErrors.
 func (c *Collection) Errors() []error {
 	c.mtx.RLock()
 	defer c.mtx.RUnlock()
 
-	return c.errs
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return append([]error(nil), c.errs...)
+}
+
+// Len returns the number of errors in the collection
+// as returned by Errors.
+func (c *Collection) Len() int {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return len(c.errs)
+}
+
+// Overflow returns the number of errors that were
+// not added because of the MaxErrors limit.
+func (c *Collection) Overflow() int {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.overflow
+}
+
+// Reset removes all errors from the collection
+// and resets the overflow counter.
+func (c *Collection) Reset() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.reset()
+}
+
+// TakeAll returns the errors in the collection
+// as returned by Errors and resets it in one atomic operation.
+// Use TakeCombined to keep the numbers of counted
+// duplicates and the overflow.
+func (c *Collection) TakeAll() []error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	errs := c.errs
+	c.reset()
+	return errs
+}
+
+func (c *Collection) reset() {
+	c.errs = nil
+	c.counts = nil
+	c.messages = nil
+	c.overflow = 0
 }
 
 // Combine returns the errors as a single error
 // combination, similar to the Combine function.
+// The numbers of counted duplicates are appended
+// to the messages of the errors like "connection refused (x57)",
+// and the number of errors that exceeded MaxErrors
+// is appended like "and 5 more".
+// Both numbers are kept when the combination
+// is combined again with other errors.
 // If the Collection is empty, then nil is returned.
 func (c *Collection) Combine() error {
 	c.mtx.RLock()
 	defer c.mtx.RUnlock()
 
+	return c.combine(1)
+}
+
+// TakeCombined returns the errors combined like Combine
+// and resets the collection in one atomic operation.
+// If the Collection is empty, then nil is returned.
+func (c *Collection) TakeCombined() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	combined := c.combine(1)
+	c.reset()
+	return combined
+}
+
+// combine returns the combined errors with the stack
+// of the caller of combine minus skip frames,
+// so a skip of 1 captures the caller of the calling method.
+func (c *Collection) combine(skip int) error {
 	if len(c.errs) == 0 {
 		return nil
 	}
-	return &combination{
-		c.errs,
-		callers(0),
+	var counts []int
+	if c.counts != nil {
+		counts = append(counts, c.counts...)
 	}
+	combined := newCombination(append([]error(nil), c.errs...), counts, c.Dedup, c.KeepDuplicates)
+	combined.dropped = c.overflow
+	combined.stack = callers(skip, nil)
+	return combined
 }
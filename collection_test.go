@@ -0,0 +1,146 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Collection(t *testing.T) {
+	var c Collection
+	assert.Equal(t, 0, c.Len())
+	assert.Nil(t, c.Errors())
+	assert.NoError(t, c.Combine())
+
+	c.Add(nil)
+	c.Add(Const("e0"))
+	c.Add(Const("e1"))
+	assert.Equal(t, 2, c.Len())
+	assert.EqualError(t, c.Combine(), "e0\ne1")
+
+	// Errors returns a copy
+	errs := c.Errors()
+	errs[0] = Const("changed")
+	assert.Equal(t, Const("e0"), c.Errors()[0])
+
+	combined := c.Combine()
+	c.Add(Const("e2"))
+	assert.EqualError(t, combined, "e0\ne1")
+
+	assert.Equal(t, []error{Const("e0"), Const("e1"), Const("e2")}, c.TakeAll())
+	assert.Equal(t, 0, c.Len())
+	assert.Nil(t, c.TakeAll())
+
+	c.Add(Const("e3"))
+	c.Reset()
+	assert.Equal(t, 0, c.Len())
+	assert.NoError(t, c.Combine())
+}
+
+func Test_CollectionMaxErrors(t *testing.T) {
+	c := &Collection{MaxErrors: 2}
+	for i := 0; i < 5; i++ {
+		c.Add(fmt.Errorf("e%d", i))
+	}
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, 3, c.Overflow())
+	assert.EqualError(t, c.Combine(), "e0\ne1\nand 3 more")
+
+	defer SetCombineFormat(SetCombineFormat(CombineFormat{Summary: true, Separator: "; "}))
+	assert.EqualError(t, c.Combine(), "5 errors occurred: e0; e1; and 3 more")
+
+	c.Reset()
+	assert.Equal(t, 0, c.Overflow())
+
+	// Duplicates don't count against the limit
+	c = &Collection{MaxErrors: 1, Dedup: DedupMessage}
+	c.Add(Const("e0"))
+	c.Add(Const("e0"))
+	c.Add(Const("e1"))
+	assert.Equal(t, 1, c.Overflow())
+	assert.EqualError(t, c.Combine(), "3 errors occurred: e0 (x2); and 1 more")
+
+	// Counts and overflow survive combining the combination again
+	assert.EqualError(t, Combine(c.Combine(), Const("e2")), "4 errors occurred: e0 (x2); e2; and 1 more")
+	assert.EqualError(t, NewCollection(c.Combine()).Combine(), "3 errors occurred: e0 (x2); and 1 more")
+	assert.EqualError(t, Combine(c.Combine()), "3 errors occurred: e0 (x2); and 1 more")
+
+	// TakeCombined keeps counts and overflow
+	assert.EqualError(t, c.TakeCombined(), "3 errors occurred: e0 (x2); and 1 more")
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, 0, c.Overflow())
+	assert.NoError(t, c.TakeCombined())
+}
+
+func Test_CollectionCombineOverflow(t *testing.T) {
+	c := &Collection{MaxErrors: 1}
+	c.Add(Const("a"))
+	c.Add(Const("b"))
+	c.Add(Const("c"))
+	assert.EqualError(t, c.Combine(), "a\nand 2 more")
+	assert.EqualError(t, Combine(c.Combine(), Const("d")), "a\nd\nand 2 more")
+}
+
+func Test_CollectionRace(t *testing.T) {
+	c := &Collection{}
+	var (
+		wg    sync.WaitGroup
+		mtx   sync.Mutex
+		taken []error
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Add(fmt.Errorf("%d/%d", i, j))
+				errs := c.Errors()
+				for range errs {
+					// Read every error while others are added
+				}
+				_ = c.Len()
+				_ = c.Combine()
+				if j%10 == 0 {
+					errs := c.TakeAll()
+					mtx.Lock()
+					taken = append(taken, errs...)
+					mtx.Unlock()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	taken = append(taken, c.TakeAll()...)
+	assert.Len(t, taken, 800)
+}
+
+func Test_CollectionRemoveFilter(t *testing.T) {
+	c := NewCollection(Wrap(io.EOF, "e0"), Const("e1"), io.EOF, Const("e2"))
+	c.Remove(io.EOF)
+	assert.Equal(t, 3, c.Len())
+	c.RemoveIs(io.EOF)
+	assert.Equal(t, []error{Const("e1"), Const("e2")}, c.Errors())
+
+	c.Add(Const("x3"))
+	isE := func(err error) bool { return strings.HasPrefix(err.Error(), "e") }
+	assert.Equal(t, []error{Const("e1"), Const("e2")}, c.Filter(isE))
+	matching, others := c.Partition(isE)
+	assert.Equal(t, []error{Const("e1"), Const("e2")}, matching)
+	assert.Equal(t, []error{Const("x3")}, others)
+	assert.Equal(t, 3, c.Len())
+
+	c.RemoveFunc(isE)
+	assert.EqualError(t, c.Combine(), "x3")
+
+	c = &Collection{Dedup: DedupMessage}
+	c.Add(Const("a"))
+	c.Add(Const("b"))
+	c.Add(Const("b"))
+	c.RemoveIs(Const("a"))
+	assert.EqualError(t, c.Combine(), "b (x2)")
+}
@@ -13,13 +13,39 @@ type multiError interface {
 	Errors() []error
 }
 
+// multiWrapper is implemented by errors of the standard library
+// like the ones returned by errors.Join and by fmt.Errorf
+// with multiple %w verbs.
+type multiWrapper interface {
+	Unwrap() []error
+}
+
 // combination combines multiple errors into one.
 // The Error method returns the strings from the individual Error methods
-// joined by the new line character '\n'.
+// joined according to the CombineFormat of the combination,
+// or the package level CombineFormat if the combination has none.
 // combination always has at least one error and returns the first error
 // as result of the Cause method.
+// Unwrap returns all combined errors so that errors.Is and errors.As
+// of the standard library check every one of them.
+// Formatted with %+v, every combined error is printed with %+v
+// numbered like "[1/3]" and with indented following lines.
 type combination struct {
-	errs []error
+	errs   []error
+	format *CombineFormat
+
+	// distinct errors with their counts
+	// if duplicates were removed or counted,
+	// errs are the distinct errors if not originals
+	distinct  []error
+	counts    []int
+	originals bool
+	dedup     DedupMode // to deduplicate filtered originals again
+
+	// dropped is the number of errors not added
+	// to a Collection because of its MaxErrors limit
+	dropped int
+
 	*stack
 }
 
@@ -29,55 +55,175 @@ type combination struct {
 // The returned non nil error will be wrapped with the callstack,
 // of the Combine call.
 // The Combination type's Error method returns the strings from the
-// individual Error methods joined by the new line character '\n'.
+// individual Error methods joined by the new line character '\n'
+// or as configured with SetCombineFormat.
 // Note that Cause(error) can only return a single error,
 // so in case of a combination error, Cause returns the cause of the first error.
+// The functions errors.Is and errors.As check all combined errors.
 // When a passed error is a combination error implementing the following interface:
 //     interface {
 //         Errors() []error
 //     }
+// or an error returned by errors.Join of the standard library,
 // then the errors are flattened to form a new combination error together
 // with the other passed errors.
+// Other errors implementing Unwrap() []error, like the ones
+// returned by fmt.Errorf with multiple %w verbs, are only flattened
+// if their Error method returns the Error results of the unwrapped
+// errors joined by '\n' so that no part of the message gets lost.
 func Combine(errs ...error) error {
-	flattened := flatten(errs)
+	return combine(nil, errs)
+}
+
+// CombineWith works like Combine but the Error method
+// of the returned combination error joins the messages
+// of the errors according to format instead of the
+// package level CombineFormat.
+// Example:
+//     return errors.CombineWith(errors.CombineSingleLine, errs...)
+func CombineWith(format CombineFormat, errs ...error) error {
+	return combine(&format, errs)
+}
+
+// combine implements Combine and CombineWith
+// with the stack of the caller of those functions.
+// If format is nil, then the Dedup mode of the package level
+// CombineFormat is applied and the combination is rendered
+// with the package level CombineFormat at the time of the Error call.
+func combine(format *CombineFormat, errs []error) error {
+	flattened, counts, dropped := flattenCounted(errs)
 
-	switch len(flattened) {
-	case 0:
+	switch {
+	case len(flattened) == 0:
 		return nil
-	case 1:
+	case len(flattened) == 1 && counts == nil && dropped == 0:
 		return &withStack{
 			flattened[0],
-			callers(0),
+			callers(1, flattened[0]),
 		}
 	}
 
-	return &combination{
-		flattened,
-		callers(0),
+	dedup := format
+	if dedup == nil {
+		global := GetCombineFormat()
+		dedup = &global
 	}
+	c := newCombination(flattened, counts, dedup.Dedup, dedup.KeepDuplicates)
+	c.format = format
+	c.dropped = dropped
+	c.stack = callers(1, nil)
+	return c
+}
+
+// newCombination returns a combination of errs without a stack
+// with the duplicates removed or kept according to dedup and keepDuplicates.
+// counts are the numbers of occurrences of errs or nil if every error occurred once.
+func newCombination(errs []error, counts []int, dedup DedupMode, keepDuplicates bool) *combination {
+	if dedup == DedupNone && counts == nil {
+		return &combination{errs: errs}
+	}
+	distinct, distinctCounts := dedup.apply(errs, counts)
+	c := &combination{
+		errs:     distinct,
+		distinct: distinct,
+		counts:   distinctCounts,
+	}
+	if keepDuplicates && counts == nil {
+		// Only originals that were not counted before can be kept
+		c.errs = errs
+		c.originals = true
+		c.dedup = dedup
+	}
+	return c
 }
 
 func flatten(errs []error) []error {
-	var flattened []error
+	flattened, _, _ := flattenCounted(errs)
+	return flattened
+}
+
+// flattenCounted flattens errs and returns the number of occurrences
+// of the flattened errors counted by deduplicating combinations,
+// or nil counts if every error occurred once,
+// and the sum of the errors dropped by the flattened combinations.
+func flattenCounted(errs []error) (flattened []error, counts []int, dropped int) {
+	add := func(err error, count int) {
+		if count != 1 && counts == nil {
+			counts = make([]int, len(flattened), len(flattened)+1)
+			for i := range counts {
+				counts[i] = 1
+			}
+		}
+		flattened = append(flattened, err)
+		if counts != nil {
+			counts = append(counts, count)
+		}
+	}
 	for _, err := range errs {
 		switch x := err.(type) {
 		case nil:
 			// ignore
+		case *combination:
+			dropped += x.dropped
+			members, memberCounts := x.errorCounts()
+			for i, member := range members {
+				if memberCounts != nil {
+					add(member, memberCounts[i])
+				} else {
+					add(member, 1)
+				}
+			}
 		case multiError:
-			flattened = append(flattened, x.Errors()...)
+			for _, member := range x.Errors() {
+				add(member, 1)
+			}
+		case multiWrapper:
+			if joined := x.Unwrap(); isJoined(err, joined) {
+				members, memberCounts, memberDropped := flattenCounted(joined)
+				dropped += memberDropped
+				for i, member := range members {
+					if memberCounts != nil {
+						add(member, memberCounts[i])
+					} else {
+						add(member, 1)
+					}
+				}
+			} else {
+				add(err, 1)
+			}
 		default:
-			flattened = append(flattened, x)
+			add(err, 1)
 		}
 	}
-	return flattened
+	return flattened, counts, dropped
+}
+
+// isJoined returns if the message of err consists only of the
+// messages of the non nil errs joined by the new line character
+// like for errors returned by errors.Join of the standard library.
+func isJoined(err error, errs []error) bool {
+	var b strings.Builder
+	for _, e := range errs {
+		if e == nil {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(multiErrorSeparator)
+		}
+		b.WriteString(e.Error())
+	}
+	return b.Len() > 0 && b.String() == err.Error()
 }
 
 // Uncombine returns multible errors
 // if err is a combination of multiple errors
-// detected by implementing the following interface:
+// detected by implementing one of the following interfaces:
 //     interface {
 //         Errors() []error
 //     }
+//     interface {
+//         Unwrap() []error
+//     }
 // It returns the passed error in a single element slice
 // if that error was not an error combination,
 // or nil if the passed error was nil.
@@ -85,21 +231,34 @@ func Uncombine(err error) []error {
 	if err == nil {
 		return nil
 	}
-	if multi, ok := err.(multiError); ok {
+	switch multi := err.(type) {
+	case multiError:
 		return multi.Errors()
+	case multiWrapper:
+		return multi.Unwrap()
 	}
 	return []error{err}
 }
 
 func (c *combination) Error() string {
-	var b strings.Builder
-	for i, err := range c.errs {
-		if i > 0 {
-			b.WriteString(multiErrorSeparator)
-		}
-		b.WriteString(err.Error())
+	format := c.format
+	if format == nil {
+		global := GetCombineFormat()
+		format = &global
+	}
+	if c.distinct != nil {
+		return format.join(c.distinct, c.counts, c.dropped)
 	}
-	return b.String()
+	return format.join(c.errs, nil, c.dropped)
+}
+
+// errorCounts returns the combined errors and their numbers of occurrences
+// for flattening, or nil counts if every error occurred once.
+func (c *combination) errorCounts() ([]error, []int) {
+	if c.distinct == nil || c.originals {
+		return c.errs, nil
+	}
+	return c.distinct, c.counts
 }
 
 func (c *combination) Cause() error {
@@ -109,18 +268,11 @@ func (c *combination) Cause() error {
 	return Cause(c.errs[0])
 }
 
-func (c *combination) Unwrap() error {
-	switch len(c.errs) {
-	case 0:
-		return nil
-	case 1, 2:
-		return c.errs[0]
-	default:
-		return &combination{
-			errs:  c.errs[:len(c.errs)-1],
-			stack: c.stack,
-		}
-	}
+// Unwrap returns the combined errors so that the functions
+// errors.Is and errors.As of the standard library
+// check all of them.
+func (c *combination) Unwrap() []error {
+	return c.errs
 }
 
 func (c *combination) Is(target error) bool {
@@ -132,6 +284,18 @@ func (c *combination) Is(target error) bool {
 	return false
 }
 
+// As finds the first of the combined errors that matches target
+// using errors.As, and if one is found, sets target to that error
+// value and returns true. Otherwise, it returns false.
+func (c *combination) As(target interface{}) bool {
+	for _, err := range c.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *combination) Errors() []error {
 	return c.errs
 }
@@ -140,9 +304,7 @@ func (c *combination) Format(s fmt.State, verb rune) {
 	switch verb {
 	case 'v':
 		if s.Flag('+') {
-			for _, e := range c.errs {
-				fmt.Fprintf(s, "%+v\n", Cause(e).Error())
-			}
+			formatMembers(s, c.errs)
 			c.stack.Format(s, verb)
 			return
 		}
@@ -153,3 +315,46 @@ func (c *combination) Format(s fmt.State, verb rune) {
 		fmt.Fprintf(s, "%q", c.Error())
 	}
 }
+
+// memberIndent indents the lines of combined errors
+// formatted with %+v after their first line.
+const memberIndent = "      "
+
+// formatMembers writes the combined errs formatted with %+v
+// numbered like "[1/3]" and separated by new lines.
+// The lines following the first line of every error are indented,
+// so the lines of nested combinations get indented further.
+func formatMembers(w io.Writer, errs []error) {
+	for i, err := range errs {
+		if i > 0 {
+			io.WriteString(w, "\n")
+		}
+		fmt.Fprintf(w, "[%d/%d]", i+1, len(errs))
+		member := fmt.Sprintf("%+v", err)
+		if isNestedCombination(err) {
+			// Start the numbered errors of the
+			// nested combination on a new line
+			member = "\n" + member
+		} else {
+			member = " " + member
+		}
+		io.WriteString(w, strings.ReplaceAll(member, "\n", "\n"+memberIndent))
+	}
+}
+
+// isNestedCombination returns if err or the only error
+// in its chain of causes with combined errors is a combination
+// that formats its errors with formatMembers.
+func isNestedCombination(err error) bool {
+	for {
+		switch err.(type) {
+		case *combination, remoteCombination:
+			return true
+		}
+		causes := children(err)
+		if len(causes) != 1 {
+			return false
+		}
+		err = causes[0]
+	}
+}
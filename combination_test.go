@@ -1,6 +1,10 @@
 package errors
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -82,3 +86,127 @@ func Test_Uncombine(t *testing.T) {
 	assert.Equal(t, e1, errs[1])
 	assert.Equal(t, e2, errs[2])
 }
+
+type codeError struct {
+	code int
+}
+
+func (e *codeError) Error() string { return fmt.Sprintf("code %d", e.code) }
+
+func Test_CombinationIsAs(t *testing.T) {
+	e0 := New("e0")
+	e1 := Wrap(&codeError{1}, "e1")
+	e2 := Wrap(&os.PathError{Op: "open", Path: "x", Err: io.EOF}, "e2")
+
+	err := Combine(e0, e1, e2)
+
+	var codeErr *codeError
+	assert.True(t, errors.As(err, &codeErr))
+	assert.Equal(t, 1, codeErr.code)
+
+	// Not the first and not the second to last error
+	var pathErr *os.PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "x", pathErr.Path)
+	assert.True(t, errors.Is(err, io.EOF))
+
+	var linkErr *os.LinkError
+	assert.False(t, errors.As(err, &linkErr))
+	assert.False(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	// Unwrap() []error of the standard library
+	unwrapped := err.(interface{ Unwrap() []error }).Unwrap()
+	assert.Equal(t, []error{e0, e1, e2}, unwrapped)
+}
+
+func Test_CombinationIsAsNested(t *testing.T) {
+	inner := Combine(New("a"), Wrap(&codeError{2}, "b"))
+	middle := Combine(New("c"), WithField(Wrapf(inner, "inner"), "key", "value"))
+	outer := Combine(fmt.Errorf("middle: %w", middle), errors.Join(New("d"), io.ErrClosedPipe))
+
+	var codeErr *codeError
+	assert.True(t, errors.As(outer, &codeErr))
+	assert.Equal(t, 2, codeErr.code)
+	assert.True(t, errors.Is(outer, io.ErrClosedPipe))
+
+	// The first matching error in depth first order is found
+	outer = Combine(Wrap(&codeError{3}, "first"), outer)
+	assert.True(t, errors.As(outer, &codeErr))
+	assert.Equal(t, 3, codeErr.code)
+}
+
+func Test_CombineJoin(t *testing.T) {
+	var (
+		e0 = New("e0")
+		e1 = New("e1")
+		e2 = New("e2")
+	)
+
+	joined := errors.Join(e0, e1)
+	err := Combine(joined, e2)
+	assert.EqualError(t, err, "e0\ne1\ne2")
+	assert.Equal(t, []error{e0, e1, e2}, Uncombine(err))
+
+	err = Combine(e2, errors.Join(Combine(e0, e1)))
+	assert.Equal(t, []error{e2, e0, e1}, Uncombine(err))
+
+	// A single joined error is flattened and wrapped with a stack
+	err = Combine(errors.Join(nil, e1))
+	assert.EqualError(t, err, "e1")
+	assert.Equal(t, e1, Cause(err))
+
+	// Combined errors can be flattened by errors.Join
+	assert.EqualError(t, errors.Join(Combine(e0, e1), e2), "e0\ne1\ne2")
+
+	// The message of fmt.Errorf with multiple %w would get lost by flattening
+	multi := fmt.Errorf("first: %w, second: %w", e0, e1)
+	err = Combine(multi, e2)
+	assert.Equal(t, []error{multi, e2}, Uncombine(err))
+	assert.Equal(t, []error{e0, e1}, Uncombine(multi))
+
+	// Wrapped joined errors are not uncombined
+	wrapped := Wrap(joined, "wrapped")
+	assert.Equal(t, []error{wrapped}, Uncombine(wrapped))
+	assert.Equal(t, e0, Cause(wrapped))
+	assert.Equal(t, e1, Cause(errors.Join(nil, WithMessage(e1, "message"))))
+}
+
+func TestFormatCombinationJoin(t *testing.T) {
+	err := Combine(
+		Wrap(errors.Join(Const("e0"), Const("e1")), "wrapped"),
+		Const("e2"),
+	)
+	out := fmt.Sprintf("%+v", err)
+	assert.Contains(t, out, "[1/2] e0\n      e1\n      wrapped\n")
+	assert.Contains(t, out, "\n[2/2] e2\n")
+
+	err = WithField(errors.Join(WithField(Const("e0"), "a", 1), WithField(Const("e1"), "b", 2)), "c", 3)
+	assert.Equal(t, map[string]interface{}{"a": 1, "b": 2, "c": 3}, Fields(err))
+}
+
+func TestFormatCombinationTree(t *testing.T) {
+	defer SetStackPolicy(SetStackPolicy(StackPolicy{Mode: StackNever}))
+
+	err := Combine(
+		Const("e0"),
+		WithMessage(Combine(Const("a"), WithMessage(Const("b"), "with b")), "nested"),
+		Const("e2"),
+	)
+	want := "" +
+		"[1/3] e0\n" +
+		"[2/3]\n" +
+		"      [1/2] a\n" +
+		"      [2/2] b\n" +
+		"            with b\n" +
+		"      nested\n" +
+		"[3/3] e2"
+	assert.Equal(t, want, fmt.Sprintf("%+v", err))
+	assert.Equal(t, "e0\nnested: a\nwith b: b\ne2", fmt.Sprintf("%v", err))
+
+	// Member stacks are kept and indented
+	SetStackPolicy(StackPolicy{Mode: StackAlways})
+	err = Combine(New("e0"), New("e1"))
+	out := fmt.Sprintf("%+v", err)
+	assert.Contains(t, out, "[1/2] e0\n      github.com/domonda/errors.TestFormatCombinationTree\n      \t")
+	assert.Contains(t, out, "\n[2/2] e1\n      github.com/domonda/errors.TestFormatCombinationTree\n      \t")
+}
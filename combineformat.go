@@ -0,0 +1,144 @@
+package errors
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// CombineFormat configures how the Error method
+// of combined errors joins the messages of the errors.
+// The zero value joins the messages with the
+// new line character '\n'.
+type CombineFormat struct {
+	// Separator is written between the messages
+	// of the combined errors.
+	// A new line character is used if Separator is empty.
+	Separator string
+
+	// Bullet is written before the message
+	// of every combined error, for example "- ".
+	Bullet string
+
+	// Summary starts the message with the number
+	// of errors like "3 errors occurred:"
+	// or "1 error occurred:" followed by
+	// the Separator if it contains a new line,
+	// or else by a space.
+	Summary bool
+
+	// MaxErrors limits the number of combined errors
+	// that are written if greater than zero.
+	// The remaining errors are summarized
+	// like "and 5 more" after the Separator.
+	MaxErrors int
+
+	// Dedup selects which errors are removed as duplicates
+	// when the errors are combined. The number of occurrences
+	// is appended to the message of an error with duplicates
+	// like "connection refused (x57)".
+	// The Dedup mode of the package level CombineFormat
+	// is applied by Combine at the time of the call.
+	Dedup DedupMode
+
+	// KeepDuplicates makes the Errors method of the combination
+	// return all original errors including the duplicates
+	// that are only counted by the Error method.
+	KeepDuplicates bool
+}
+
+var (
+	// CombineLines is the default CombineFormat
+	// that joins the messages with new lines.
+	CombineLines = CombineFormat{}
+
+	// CombineSingleLine joins the messages
+	// with a semicolon for single line log formats
+	// and HTTP headers.
+	CombineSingleLine = CombineFormat{Separator: "; "}
+
+	// CombineBullets starts with a summary line
+	// and writes every message as bullet point.
+	CombineBullets = CombineFormat{Bullet: "- ", Summary: true}
+)
+
+var combineFormat atomic.Value // CombineFormat
+
+// SetCombineFormat sets the package level CombineFormat
+// used by the Error method of errors combined with Combine
+// or Collection.Combine, and returns the previous one.
+// It is safe to call SetCombineFormat concurrently
+// with the formatting of errors.
+// Example:
+//     errors.SetCombineFormat(errors.CombineFormat{
+//         Separator: "; ",
+//         MaxErrors: 10,
+//     })
+func SetCombineFormat(format CombineFormat) (previous CombineFormat) {
+	previous = GetCombineFormat()
+	combineFormat.Store(format)
+	return previous
+}
+
+// GetCombineFormat returns the package level CombineFormat.
+func GetCombineFormat() CombineFormat {
+	format, _ := combineFormat.Load().(CombineFormat)
+	return format
+}
+
+// join returns the messages of errs joined according to format.
+// counts are the numbers of occurrences of errs
+// or nil if every error occurred once.
+// dropped is the number of errors that were not combined
+// because of a limit and are summarized with the truncated errors.
+func (format CombineFormat) join(errs []error, counts []int, dropped int) string {
+	separator := format.Separator
+	if separator == "" {
+		separator = multiErrorSeparator
+	}
+	written := len(errs)
+	if format.MaxErrors > 0 && written > format.MaxErrors {
+		written = format.MaxErrors
+	}
+
+	total := len(errs) + dropped
+	for _, count := range counts {
+		total += count - 1
+	}
+
+	var b strings.Builder
+	if format.Summary {
+		if total == 1 {
+			b.WriteString("1 error occurred:")
+		} else {
+			b.WriteString(strconv.Itoa(total) + " errors occurred:")
+		}
+		if strings.Contains(separator, "\n") {
+			b.WriteString(separator)
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	for i, err := range errs[:written] {
+		if i > 0 {
+			b.WriteString(separator)
+		}
+		b.WriteString(format.Bullet)
+		b.WriteString(err.Error())
+		if counts != nil && counts[i] > 1 {
+			b.WriteString(" (x" + strconv.Itoa(counts[i]) + ")")
+		}
+	}
+	more := dropped
+	for i := written; i < len(errs); i++ {
+		if counts != nil {
+			more += counts[i]
+		} else {
+			more++
+		}
+	}
+	if more > 0 {
+		b.WriteString(separator + "and " + strconv.Itoa(more) + " more")
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CombineFormat(t *testing.T) {
+	errs := []error{Const("e0"), Const("e1"), Const("e2")}
+
+	tests := []struct {
+		format CombineFormat
+		want   string
+	}{
+		{CombineLines, "e0\ne1\ne2"},
+		{CombineSingleLine, "e0; e1; e2"},
+		{CombineBullets, "3 errors occurred:\n- e0\n- e1\n- e2"},
+		{CombineFormat{Separator: ", ", Summary: true}, "3 errors occurred: e0, e1, e2"},
+		{CombineFormat{MaxErrors: 2}, "e0\ne1\nand 1 more"},
+		{CombineFormat{MaxErrors: 3}, "e0\ne1\ne2"},
+		{CombineFormat{Separator: "; ", Bullet: "* ", Summary: true, MaxErrors: 1}, "3 errors occurred: * e0; and 2 more"},
+	}
+	for _, tt := range tests {
+		assert.EqualError(t, CombineWith(tt.format, errs...), tt.want, "%#v", tt.format)
+	}
+
+	// Single errors are not combined
+	assert.EqualError(t, CombineWith(CombineBullets, nil, Const("e0")), "e0")
+	assert.NoError(t, CombineWith(CombineBullets))
+
+	// Collections always combine
+	c := NewCollection(Const("e0"))
+	defer SetCombineFormat(SetCombineFormat(CombineBullets))
+	assert.EqualError(t, c.Combine(), "1 error occurred:\n- e0")
+}
+
+func Test_SetCombineFormat(t *testing.T) {
+	err := Combine(Const("e0"), Const("e1"))
+	assert.EqualError(t, err, "e0\ne1")
+
+	previous := SetCombineFormat(CombineSingleLine)
+	assert.Equal(t, CombineLines, previous)
+	assert.EqualError(t, err, "e0; e1")
+	assert.EqualError(t, NewCollection(Const("e0"), Const("e1")).Combine(), "e0; e1")
+
+	// The format of CombineWith is not changed by the package level format
+	assert.EqualError(t, CombineWith(CombineLines, Const("e0"), Const("e1")), "e0\ne1")
+
+	SetCombineFormat(previous)
+	assert.EqualError(t, err, "e0\ne1")
+}
@@ -0,0 +1,110 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DedupMode selects which errors are considered duplicates
+// when combining errors with a CombineFormat or in a Collection.
+// Only the first of duplicate errors is kept together with
+// the number of its duplicates that is rendered by the Error method
+// of the combination like "connection refused (x57)".
+type DedupMode int
+
+const (
+	// DedupNone keeps all errors.
+	DedupNone DedupMode = iota
+
+	// DedupIdentity removes errors identical to a previous error,
+	// pointers to the same value or comparable values equal
+	// compared with ==.
+	DedupIdentity
+
+	// DedupIs removes errors for which errors.Is returns true
+	// for the cause of a previous error, like all errors
+	// wrapping the same sentinel error.
+	DedupIs
+
+	// DedupMessage removes errors with the same
+	// message as a previous error.
+	DedupMessage
+)
+
+// String returns the name of the mode.
+func (mode DedupMode) String() string {
+	switch mode {
+	case DedupNone:
+		return "DedupNone"
+	case DedupIdentity:
+		return "DedupIdentity"
+	case DedupIs:
+		return "DedupIs"
+	case DedupMessage:
+		return "DedupMessage"
+	}
+	return fmt.Sprintf("DedupMode(%d)", int(mode))
+}
+
+// isDuplicate returns if err is a duplicate of the previous distinct error.
+func (mode DedupMode) isDuplicate(err, distinct error) bool {
+	switch mode {
+	case DedupIdentity:
+		return identical(err, distinct)
+	case DedupIs:
+		return errors.Is(err, Cause(distinct))
+	case DedupMessage:
+		return err.Error() == distinct.Error()
+	}
+	return false
+}
+
+// index returns the index of the first of the distinct errors
+// that err is a duplicate of, or -1 if there is none.
+func (mode DedupMode) index(err error, distinct []error) int {
+	if mode == DedupNone {
+		return -1
+	}
+	for i, d := range distinct {
+		if mode.isDuplicate(err, d) {
+			return i
+		}
+	}
+	return -1
+}
+
+// apply returns the distinct errs and the numbers of their duplicates.
+// counts are the already counted numbers of occurrences of errs
+// or nil if every error occurred once.
+func (mode DedupMode) apply(errs []error, counts []int) (distinct []error, distinctCounts []int) {
+	// Index messages to avoid calling the Error
+	// method for every pair of errors
+	var messages map[string]int
+	if mode == DedupMessage {
+		messages = make(map[string]int)
+	}
+	for i, err := range errs {
+		count := 1
+		if counts != nil {
+			count = counts[i]
+		}
+		index := -1
+		if messages != nil {
+			if j, ok := messages[err.Error()]; ok {
+				index = j
+			}
+		} else {
+			index = mode.index(err, distinct)
+		}
+		if index == -1 {
+			if messages != nil {
+				messages[err.Error()] = len(distinct)
+			}
+			distinct = append(distinct, err)
+			distinctCounts = append(distinctCounts, count)
+		} else {
+			distinctCounts[index] += count
+		}
+	}
+	return distinct, distinctCounts
+}
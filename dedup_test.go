@@ -0,0 +1,132 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listError is not comparable
+type listError []string
+
+func (e listError) Error() string { return fmt.Sprint([]string(e)) }
+
+// valueError is comparable but panics when compared
+// if value holds a not comparable value
+type valueError struct {
+	value interface{}
+}
+
+func (e valueError) Error() string { return fmt.Sprint(e.value) }
+
+func Test_CombineDedup(t *testing.T) {
+	const errRefused = Const("connection refused")
+
+	var errs []error
+	for i := 0; i < 57; i++ {
+		errs = append(errs, Wrap(errRefused, "query"))
+	}
+	errs = append(errs, Const("timeout"), Const("timeout"))
+
+	err := CombineWith(CombineFormat{Dedup: DedupMessage}, errs...)
+	assert.EqualError(t, err, "query: connection refused (x57)\ntimeout (x2)")
+	assert.Len(t, Uncombine(err), 2)
+	assert.True(t, errors.Is(err, errRefused))
+
+	err = CombineWith(CombineFormat{Dedup: DedupIs}, errs...)
+	assert.EqualError(t, err, "query: connection refused (x57)\ntimeout (x2)")
+
+	// Wrapped errors are not identical
+	err = CombineWith(CombineFormat{Dedup: DedupIdentity}, errs...)
+	assert.Len(t, Uncombine(err), 58)
+
+	// All originals are kept if requested
+	err = CombineWith(CombineFormat{Dedup: DedupMessage, KeepDuplicates: true, Summary: true, Separator: "; "}, errs...)
+	assert.EqualError(t, err, "59 errors occurred: query: connection refused (x57); timeout (x2)")
+	assert.Equal(t, errs, Uncombine(err))
+
+	// Non comparable errors are never identical
+	err = CombineWith(CombineFormat{Dedup: DedupIdentity}, listError{"a"}, listError{"a"})
+	assert.EqualError(t, err, "[a]\n[a]")
+
+	// Comparing must not panic
+	err = CombineWith(CombineFormat{Dedup: DedupIdentity}, valueError{[]int{1}}, valueError{[]int{1}}, valueError{1}, valueError{1})
+	assert.EqualError(t, err, "[1]\n[1]\n1 (x2)")
+}
+
+func Test_CombineDedupFlatten(t *testing.T) {
+	inner := CombineWith(CombineFormat{Dedup: DedupMessage}, Const("e0"), Const("e0"), Const("e1"))
+	assert.EqualError(t, inner, "e0 (x2)\ne1")
+
+	// Counts survive flattening
+	err := CombineWith(CombineFormat{Dedup: DedupMessage}, Const("e1"), inner)
+	assert.EqualError(t, err, "e1 (x2)\ne0 (x2)")
+
+	// Without deduplication the counts are still rendered
+	err = Combine(inner, Const("e2"))
+	assert.EqualError(t, err, "e0 (x2)\ne1\ne2")
+
+	defer SetCombineFormat(SetCombineFormat(CombineFormat{Dedup: DedupIdentity}))
+	err = Combine(Const("e0"), Const("e0"))
+	assert.EqualError(t, err, "e0 (x2)")
+	assert.Equal(t, Const("e0"), Cause(err))
+}
+
+func Test_CollectionDedup(t *testing.T) {
+	c := &Collection{Dedup: DedupMessage}
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.Add(fmt.Errorf("connection refused"))
+			if i%50 == 0 {
+				c.Add(fmt.Errorf("timeout"))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, c.Errors(), 2)
+	assert.Contains(t, c.Combine().Error(), "connection refused (x100)")
+	assert.Contains(t, c.Combine().Error(), "timeout (x2)")
+
+	c = &Collection{Dedup: DedupMessage, KeepDuplicates: true}
+	c.Add(Const("e0"))
+	c.Add(Const("e1"))
+	c.Add(Const("e0"))
+	assert.Len(t, c.Errors(), 3)
+	assert.EqualError(t, c.Combine(), "e0 (x2)\ne1")
+
+	c = &Collection{Dedup: DedupIdentity}
+	c.Add(Const("e0"))
+	c.Add(Const("e1"))
+	c.Add(Const("e0"))
+	c.Remove(Const("e0"))
+	assert.EqualError(t, c.Combine(), "e1")
+
+	// Message indices stay valid after removing errors
+	c = &Collection{Dedup: DedupMessage}
+	c.Add(Const("e0"))
+	c.Add(Const("e1"))
+	c.Add(Const("e2"))
+	c.Remove(Const("e0"))
+	c.Add(Const("e2"))
+	c.Add(Const("e0"))
+	assert.EqualError(t, c.Combine(), "e1\ne2 (x2)\ne0")
+	c.Reset()
+	c.Add(Const("e2"))
+	assert.EqualError(t, c.Combine(), "e2")
+}
+
+func Test_CombineDedupTruncated(t *testing.T) {
+	a, b := Const("a"), Const("b")
+	err := CombineWith(CombineFormat{Separator: "; ", Summary: true, MaxErrors: 1, Dedup: DedupMessage}, a, b, b, b, b, b)
+	assert.EqualError(t, err, "6 errors occurred: a; and 5 more")
+
+	err = CombineWith(CombineFormat{Separator: "; ", MaxErrors: 1, Dedup: DedupMessage}, b, b, a, a, Const("c"))
+	assert.EqualError(t, err, "b (x2); and 3 more")
+}
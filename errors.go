@@ -26,6 +26,42 @@
 // functions destructure errors.Wrap into its component operations of annotating
 // an error with a stack trace and an a message, respectively.
 //
+// Attaching structured fields to an error
+//
+// The errors.WithField and errors.WithFields functions annotate an error
+// with key/value fields without changing its message.
+// errors.Fields returns the merged fields of the whole error chain:
+//
+//     err = errors.WithField(err, "documentID", documentID)
+//     ...
+//     log.Println(err, errors.Fields(err))
+//
+// Classifying errors with codes
+//
+// The errors.WithCode function annotates an error with a code of a
+// user defined comparable type that is returned by errors.Code
+// through any wrapping and combining of the error:
+//
+//     err = errors.WithCode(err, CodeNotFound)
+//     ...
+//     switch errors.Code(err) {
+//     case CodeNotFound:
+//             ...
+//     }
+//
+// Separating public and internal messages
+//
+// The errors.WithPublicMessage and errors.Publicf functions annotate an error
+// with a message that is safe to be shown to end users, while the Error method
+// keeps returning the detailed internal message for logs.
+// errors.PublicMessage returns the outermost public message
+// or a generic default message:
+//
+//     err = errors.WithPublicMessage(err, "The document could not be loaded")
+//     ...
+//     log.Printf("%+v", err)
+//     showToUser(errors.PublicMessage(err))
+//
 // Retrieving the cause of an error
 //
 // Using errors.Wrap constructs a stack of errors, adding context to the
@@ -51,6 +87,10 @@
 // causer interface is not exported by this package, but is considered a part
 // of stable public API.
 //
+// errors.RootCauses returns the causes of all errors combined with
+// errors.Combine or errors.Join, and errors.Walk traverses the whole
+// tree of wrapped and combined errors.
+//
 // Formatted printing of errors
 //
 // All error values returned from this package implement fmt.Formatter and can
@@ -62,6 +102,11 @@
 //     %+v   extended format. Each Frame of the error's StackTrace will
 //           be printed in detail.
 //
+// Frames like runtime.goexit can be hidden from the extended format
+// with a FrameFilter set via errors.SetStackFormat,
+// and the source file paths can be trimmed with a PathTrimmer
+// like errors.TrimModuleRoot.
+//
 // Retrieving the stack trace of an error or wrapper
 //
 // New, Errorf, Wrap, and Wrapf record a stack trace at the point they are
@@ -88,7 +133,36 @@
 // stackTracer interface is not exported by this package, but is considered a part
 // of stable public API.
 //
+// StackTrace.Resolve returns a ResolvedStackTrace with the function names,
+// source files, and line numbers of the frames, including inlined calls.
+// Resolved stack traces stay meaningful after they were serialized
+// and can be attached to an error with errors.WithResolvedStackTrace.
+// All errors with a stack trace of this package implement
+//
+//     type resolvedStackTracer interface {
+//             ResolvedStackTrace() errors.ResolvedStackTrace
+//     }
+//
 // See the documentation for Frame.Format for more details.
+//
+// Configuring the capture of stack traces
+//
+// How stack traces are captured is configured by a StackPolicy
+// with a maximum depth, sampling, and a mode to never capture stack traces
+// or only when the wrapped error has none yet.
+// By default only the frame of the calling function is captured
+// when wrapping an error that already has a stack trace.
+// When formatted with %+v, the frames a stack trace has in common
+// with the stack trace of the wrapped error are printed as "... N more".
+// The package level policy is set with errors.SetStackPolicy or with the
+// environment variable ERRORS_STACK_POLICY, for example:
+//
+//     ERRORS_STACK_POLICY=mode=firstwrap,depth=64
+//
+// The methods of StackPolicy like StackPolicy.Wrap apply a policy
+// to a single call:
+//
+//     return errors.StackPolicy{Mode: errors.StackNever}.Wrap(err, "hot path")
 package errors
 
 import (
@@ -105,7 +179,7 @@ var Type = reflect.TypeOf((*error)(nil)).Elem()
 func New(message string) error {
 	return &fundamental{
 		msg:   message,
-		stack: callers(0),
+		stack: callers(0, nil),
 	}
 }
 
@@ -115,7 +189,7 @@ func New(message string) error {
 func Errorf(format string, args ...interface{}) error {
 	return &fundamental{
 		msg:   fmt.Sprintf(format, args...),
-		stack: callers(0),
+		stack: callers(0, nil),
 	}
 }
 
@@ -151,7 +225,7 @@ func WithStack(err error) error {
 	}
 	return &withStack{
 		err,
-		callers(0),
+		callers(0, err),
 	}
 }
 
@@ -163,13 +237,28 @@ func WithStackSkip(skip int, err error) error {
 	}
 	return &withStack{
 		err,
-		callers(skip),
+		callers(skip, err),
 	}
 }
 
+// WithResolvedStackTrace annotates err with an already resolved stack trace,
+// for example one that was received from another process.
+// If err is nil, WithResolvedStackTrace returns nil.
+func WithResolvedStackTrace(err error, stack ResolvedStackTrace) error {
+	if err == nil {
+		return nil
+	}
+	return &withStack{
+		err,
+		stack,
+	}
+}
+
+// withStack annotates an error with a stack captured in this process
+// or with a ResolvedStackTrace.
 type withStack struct {
 	error
-	*stack
+	callStack
 }
 
 func (w *withStack) Cause() error {
@@ -185,7 +274,13 @@ func (w *withStack) Format(s fmt.State, verb rune) {
 	case 'v':
 		if s.Flag('+') {
 			fmt.Fprintf(s, "%+v", w.Cause())
-			w.stack.Format(s, verb)
+			if st, ok := w.callStack.(*stack); ok {
+				// Don't repeat the frames the stack
+				// has in common with the stack of the cause
+				st.formatMerged(s, causeStackTrace(w.error))
+			} else {
+				w.callStack.Format(s, verb)
+			}
 			return
 		}
 		fallthrough
@@ -209,7 +304,7 @@ func Wrap(err error, message string) error {
 	}
 	return &withStack{
 		err,
-		callers(0),
+		callers(0, err),
 	}
 }
 
@@ -226,7 +321,7 @@ func WrapSkip(skip int, err error, message string) error {
 	}
 	return &withStack{
 		err,
-		callers(skip),
+		callers(skip, err),
 	}
 }
 
@@ -243,7 +338,7 @@ func Wrapf(err error, format string, args ...interface{}) error {
 	}
 	return &withStack{
 		err,
-		callers(0),
+		callers(0, err),
 	}
 }
 
@@ -260,7 +355,7 @@ func WrapfSkip(skip int, err error, format string, args ...interface{}) error {
 	}
 	return &withStack{
 		err,
-		callers(skip),
+		callers(skip, err),
 	}
 }
 
@@ -315,6 +410,11 @@ func (w *withMessage) Format(s fmt.State, verb rune) {
 //            Cause() error
 //     }
 //
+// For errors implementing Unwrap() []error like the ones
+// returned by errors.Join of the standard library,
+// the cause of the first non nil unwrapped error is returned.
+// Use RootCauses to get the causes of all combined errors.
+//
 // If the error does not implement Cause, the original error will
 // be returned. If the error is nil, nil will be returned without further
 // investigation.
@@ -332,9 +432,24 @@ func Cause(err error) error {
 			err = e.Cause()
 		case wrapper:
 			err = e.Unwrap()
+		case multiWrapper:
+			first := firstNonNil(e.Unwrap())
+			if first == nil {
+				return err
+			}
+			err = first
 		default:
 			return err
 		}
 	}
 	return err
 }
+
+func firstNonNil(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WithField annotates err with a structured key/value field.
+// The Error method of the returned error returns err.Error() unchanged,
+// the field can be retrieved with the Fields function.
+// If err is nil, WithField returns nil.
+func WithField(err error, key string, value interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return &withFields{
+		cause:  err,
+		fields: map[string]interface{}{key: value},
+	}
+}
+
+// WithFields annotates err with structured key/value fields.
+// The Error method of the returned error returns err.Error() unchanged,
+// the fields can be retrieved with the Fields function.
+// The passed map is copied.
+// If err is nil, WithFields returns nil.
+func WithFields(err error, fields map[string]interface{}) error {
+	if err == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		copied[key] = value
+	}
+	return &withFields{
+		cause:  err,
+		fields: copied,
+	}
+}
+
+type withFields struct {
+	cause  error
+	fields map[string]interface{}
+}
+
+func (w *withFields) Error() string {
+	return w.cause.Error()
+}
+
+func (w *withFields) Cause() error {
+	return w.cause
+}
+
+func (w *withFields) Unwrap() error {
+	return w.cause
+}
+
+func (w *withFields) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%+v\n", w.Cause())
+			io.WriteString(s, formatFields(w.fields))
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, w.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", w.Error())
+	}
+}
+
+// Fields returns all fields attached with WithField or WithFields
+// to err or any error in its chain, including the members
+// of error combinations.
+// The fields are merged from the innermost to the outermost error,
+// so a field of an outer error overrides a field with the same key
+// of an error that it wraps.
+// The fields of combined errors are merged in the order of the combination,
+// so later errors override fields with the same key of earlier errors.
+// Returns nil if there are no fields.
+func Fields(err error) map[string]interface{} {
+	fields := make(map[string]interface{})
+	mergeFields(fields, err)
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+func mergeFields(dst map[string]interface{}, err error) {
+	type causer interface {
+		Cause() error
+	}
+	type wrapper interface {
+		Unwrap() error
+	}
+	type remote interface {
+		remote() *remoteError
+	}
+
+	switch e := err.(type) {
+	case nil:
+		return
+	case *withFields:
+		mergeFields(dst, e.cause)
+		for key, value := range e.fields {
+			dst[key] = value
+		}
+	case remote:
+		r := e.remote()
+		mergeFields(dst, r.cause)
+		for _, member := range r.errs {
+			mergeFields(dst, member)
+		}
+		for key, value := range r.fields {
+			dst[key] = value
+		}
+	case multiError:
+		for _, member := range e.Errors() {
+			mergeFields(dst, member)
+		}
+	case multiWrapper:
+		for _, member := range e.Unwrap() {
+			mergeFields(dst, member)
+		}
+	case causer:
+		mergeFields(dst, e.Cause())
+	case wrapper:
+		mergeFields(dst, e.Unwrap())
+	}
+}
+
+// formatFields formats fields as space separated key=value pairs sorted by key.
+func formatFields(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%s=%v", key, fields[key])
+	}
+	return b.String()
+}
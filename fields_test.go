@@ -0,0 +1,75 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithFields(t *testing.T) {
+	assert.NoError(t, WithField(nil, "key", "value"))
+	assert.NoError(t, WithFields(nil, map[string]interface{}{"key": "value"}))
+
+	err := WithField(io.EOF, "documentID", 123)
+	assert.EqualError(t, err, "EOF")
+	assert.Equal(t, io.EOF, Cause(err))
+	assert.True(t, errors.Is(err, io.EOF))
+
+	fields := map[string]interface{}{"a": 1}
+	err = WithFields(io.EOF, fields)
+	fields["b"] = 2
+	assert.Equal(t, map[string]interface{}{"a": 1}, Fields(err))
+}
+
+func Test_Fields(t *testing.T) {
+	assert.Nil(t, Fields(nil))
+	assert.Nil(t, Fields(io.EOF))
+	assert.Nil(t, Fields(Wrap(io.EOF, "no fields")))
+
+	// Outer fields override inner fields
+	err := WithField(io.EOF, "a", "inner")
+	err = Wrap(err, "wrapped")
+	err = WithFields(err, map[string]interface{}{"a": "outer", "b": 2})
+	err = fmt.Errorf("stdlib: %w", err)
+	assert.Equal(t, map[string]interface{}{"a": "outer", "b": 2}, Fields(err))
+
+	// Fields survive flattening of combinations
+	e0 := WithField(New("e0"), "e0", true)
+	e1 := WithField(New("e1"), "shared", 1)
+	e2 := WithFields(New("e2"), map[string]interface{}{"shared": 2, "e2": true})
+	err = Combine(e0, Combine(e1, e2))
+	assert.Len(t, Uncombine(err), 3)
+	assert.Equal(t, map[string]interface{}{"e0": true, "e2": true, "shared": 2}, Fields(err))
+
+	err = WithField(err, "shared", 3)
+	assert.Equal(t, map[string]interface{}{"e0": true, "e2": true, "shared": 3}, Fields(err))
+}
+
+func TestFormatWithFields(t *testing.T) {
+	tests := []struct {
+		error
+		format string
+		want   []string
+	}{{
+		WithField(io.EOF, "key", "value"),
+		"%s",
+		[]string{"EOF"},
+	}, {
+		WithFields(io.EOF, map[string]interface{}{"b": 2, "a": 1}),
+		"%+v",
+		[]string{"EOF", "a=1 b=2"},
+	}, {
+		Wrap(WithField(io.EOF, "key", "value"), "wrapped"),
+		"%+v",
+		[]string{"EOF", "key=value", "wrapped",
+			"github.com/domonda/errors.TestFormatWithFields\n" +
+				"\t.+/github.com/domonda/errors/fields_test.go:65"},
+	}}
+
+	for i, tt := range tests {
+		testFormatCompleteCompare(t, i, tt.error, tt.format, tt.want, true)
+	}
+}
@@ -0,0 +1,158 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// FrameFilter returns true for stack frames
+// that should be hidden when formatting
+// stack traces with %+v.
+type FrameFilter func(frame ResolvedFrame) bool
+
+// FilterPackages returns a FrameFilter that hides the frames
+// of functions from packages with an import path
+// equal to one of the passed package paths
+// or starting with one of them followed by a slash.
+// Example:
+//     errors.FilterPackages("net/http", "github.com/example/middleware")
+func FilterPackages(packages ...string) FrameFilter {
+	return func(frame ResolvedFrame) bool {
+		for _, pkg := range packages {
+			if frame.Package == pkg || strings.HasPrefix(frame.Package, pkg+"/") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FilterFunctions returns a FrameFilter that hides the frames
+// of functions with a package path-qualified name matching re.
+func FilterFunctions(re *regexp.Regexp) FrameFilter {
+	return func(frame ResolvedFrame) bool {
+		return re.MatchString(frame.Function)
+	}
+}
+
+// FilterAny returns a FrameFilter that hides the frames
+// hidden by any of the passed filters.
+// Nil filters are ignored.
+func FilterAny(filters ...FrameFilter) FrameFilter {
+	return func(frame ResolvedFrame) bool {
+		for _, filter := range filters {
+			if filter != nil && filter(frame) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var (
+	// FilterRuntime hides the frames of the runtime package
+	// like runtime.goexit and runtime.main.
+	FilterRuntime = FilterPackages("runtime")
+
+	// FilterTesting hides the frames of the testing package
+	// like testing.tRunner.
+	FilterTesting = FilterPackages("testing")
+
+	// FilterStdlib hides the frames of all standard library packages
+	// detected by the first element of their import path
+	// not containing a dot, with the exception of the main package.
+	FilterStdlib FrameFilter = func(frame ResolvedFrame) bool {
+		if frame.Package == "" || frame.Package == "main" {
+			return false
+		}
+		first := frame.Package
+		if slash := strings.IndexByte(first, '/'); slash != -1 {
+			first = first[:slash]
+		}
+		return !strings.Contains(first, ".")
+	}
+)
+
+// StackFormat configures how stack traces
+// are formatted with %+v.
+type StackFormat struct {
+	// Filter hides all frames for which it returns true.
+	// No frames are hidden if Filter is nil.
+	Filter FrameFilter
+
+	// CollapseFiltered replaces every run of
+	// consecutive frames hidden by Filter with
+	// a single line like "... 3 frames hidden".
+	CollapseFiltered bool
+
+	// TrimPath returns the printed source file path of a frame.
+	// The complete path is printed if TrimPath is nil.
+	TrimPath PathTrimmer
+}
+
+// file returns the source file path of frame
+// trimmed with format.TrimPath.
+func (format StackFormat) file(frame ResolvedFrame) string {
+	if format.TrimPath == nil {
+		return frame.File
+	}
+	return format.TrimPath(frame)
+}
+
+var stackFormat atomic.Value // StackFormat
+
+// SetStackFormat sets the package level StackFormat
+// used by all types of this package when formatting
+// stack traces with %+v, and returns the previous one.
+// It is safe to call SetStackFormat concurrently
+// with the formatting of errors.
+// Example:
+//     errors.SetStackFormat(errors.StackFormat{
+//         Filter:           errors.FilterAny(errors.FilterRuntime, errors.FilterTesting),
+//         CollapseFiltered: true,
+//     })
+func SetStackFormat(format StackFormat) (previous StackFormat) {
+	previous = GetStackFormat()
+	stackFormat.Store(format)
+	return previous
+}
+
+// GetStackFormat returns the package level StackFormat.
+func GetStackFormat() StackFormat {
+	format, _ := stackFormat.Load().(StackFormat)
+	return format
+}
+
+// writeFrames writes the frames formatted like %+v,
+// each prefixed with a new line, and applies the filter
+// of the package level StackFormat.
+func writeFrames(w io.Writer, frames []ResolvedFrame) {
+	format := GetStackFormat()
+	hidden := 0
+	for _, f := range frames {
+		if format.Filter != nil && format.Filter(f) {
+			hidden++
+			continue
+		}
+		if hidden > 0 && format.CollapseFiltered {
+			writeHiddenFrames(w, hidden)
+		}
+		hidden = 0
+		io.WriteString(w, "\n")
+		f.writeDetailed(w, format)
+	}
+	if hidden > 0 && format.CollapseFiltered {
+		writeHiddenFrames(w, hidden)
+	}
+}
+
+func writeHiddenFrames(w io.Writer, hidden int) {
+	if hidden == 1 {
+		io.WriteString(w, "\n\t... 1 frame hidden")
+		return
+	}
+	fmt.Fprintf(w, "\n\t... %d frames hidden", hidden)
+}
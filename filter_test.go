@@ -0,0 +1,77 @@
+package errors
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FrameFilters(t *testing.T) {
+	var (
+		goexit   = ResolvedFrame{Function: "runtime.goexit", Package: "runtime"}
+		tRunner  = ResolvedFrame{Function: "testing.tRunner", Package: "testing"}
+		serve    = ResolvedFrame{Function: "net/http.HandlerFunc.ServeHTTP", Package: "net/http"}
+		httputl  = ResolvedFrame{Function: "net/http/httputil.(*ReverseProxy).ServeHTTP", Package: "net/http/httputil"}
+		httpx    = ResolvedFrame{Function: "net/httpx.Serve", Package: "net/httpx"}
+		mainMain = ResolvedFrame{Function: "main.main", Package: "main"}
+		own      = ResolvedFrame{Function: "github.com/domonda/errors.New", Package: "github.com/domonda/errors"}
+	)
+
+	assert.True(t, FilterRuntime(goexit))
+	assert.False(t, FilterRuntime(tRunner))
+	assert.True(t, FilterTesting(tRunner))
+	assert.False(t, FilterTesting(own))
+
+	for _, f := range []ResolvedFrame{goexit, tRunner, serve, httputl, httpx} {
+		assert.True(t, FilterStdlib(f), f.Function)
+	}
+	assert.False(t, FilterStdlib(mainMain))
+	assert.False(t, FilterStdlib(own))
+	assert.False(t, FilterStdlib(ResolvedFrame{}))
+
+	http := FilterPackages("net/http")
+	assert.True(t, http(serve))
+	assert.True(t, http(httputl))
+	assert.False(t, http(httpx))
+
+	funcs := FilterFunctions(regexp.MustCompile(`ServeHTTP$`))
+	assert.True(t, funcs(serve))
+	assert.True(t, funcs(httputl))
+	assert.False(t, funcs(httpx))
+
+	runtimeOrTesting := FilterAny(nil, FilterRuntime, FilterTesting)
+	assert.True(t, runtimeOrTesting(goexit))
+	assert.True(t, runtimeOrTesting(tRunner))
+	assert.False(t, runtimeOrTesting(serve))
+}
+
+func TestFormatFilteredStack(t *testing.T) {
+	defer SetStackFormat(SetStackFormat(StackFormat{Filter: FilterAny(FilterRuntime, FilterTesting)}))
+
+	err := New("error")
+	testFormatRegexp(t, 0, err, "%+v", "error\n"+
+		"github.com/domonda/errors.TestFormatFilteredStack\n"+
+		"\t.+/github.com/domonda/errors/filter_test.go:53$")
+
+	SetStackFormat(StackFormat{Filter: FilterTesting, CollapseFiltered: true})
+	testFormatRegexp(t, 1, err, "%+v", "error\n"+
+		"github.com/domonda/errors.TestFormatFilteredStack\n"+
+		"\t.+/github.com/domonda/errors/filter_test.go:53\n"+
+		"\t\\.\\.\\. 1 frame hidden\n"+
+		"runtime.goexit\n"+
+		"\t.+$")
+
+	SetStackFormat(StackFormat{Filter: FilterStdlib, CollapseFiltered: true})
+	st := err.(interface{ StackTrace() StackTrace }).StackTrace()
+	testFormatRegexp(t, 2, st, "%+v", "\n"+
+		"github.com/domonda/errors.TestFormatFilteredStack\n"+
+		"\t.+/github.com/domonda/errors/filter_test.go:53\n"+
+		"\t\\.\\.\\. 2 frames hidden$")
+
+	// Single frames are never filtered
+	testFormatRegexp(t, 3, st[len(st)-1], "%+v", "runtime.goexit\n\t.+")
+
+	assert.Equal(t, "error", fmt.Sprint(err))
+}
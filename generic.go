@@ -0,0 +1,68 @@
+//go:build go1.18
+// +build go1.18
+
+package errors
+
+// AsType returns the first error of type T in the tree of err
+// and its causes and combined errors as traversed by Walk.
+// Like errors.As, an error also matches if it has an
+// As(interface{}) bool method that returns true for a *T,
+// except for combinations where only the combined errors are checked.
+// In contrast to errors.As, errors implementing only
+// the Cause() error method are also traversed.
+// Example:
+//     if e, ok := errors.AsType[*os.PathError](err); ok {
+//         log.Println(e.Path)
+//     }
+func AsType[T any](err error) (match T, ok bool) {
+	Walk(err, func(e error, depth int) bool {
+		if ok {
+			return false
+		}
+		match, ok = asType[T](e)
+		return !ok
+	})
+	return match, ok
+}
+
+// FindAll returns all errors of type T in the tree of err
+// and its causes and combined errors as traversed by Walk
+// and matched like by AsType.
+// If no error matches, nil is returned.
+func FindAll[T any](err error) []T {
+	var matches []T
+	Walk(err, func(e error, depth int) bool {
+		if match, ok := asType[T](e); ok {
+			matches = append(matches, match)
+		}
+		return true
+	})
+	return matches
+}
+
+// HasType returns if there is an error of type T in the tree of err
+// and its causes and combined errors as matched by AsType.
+func HasType[T any](err error) bool {
+	_, ok := AsType[T](err)
+	return ok
+}
+
+func asType[T any](err error) (match T, ok bool) {
+	type aser interface {
+		As(interface{}) bool
+	}
+
+	if match, ok = err.(T); ok {
+		return match, true
+	}
+	switch e := err.(type) {
+	case multiError, multiWrapper:
+		// The combined errors are walked anyway
+		return match, false
+	case aser:
+		if e.As(&match) {
+			return match, true
+		}
+	}
+	return match, false
+}
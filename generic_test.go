@@ -0,0 +1,93 @@
+//go:build go1.18
+// +build go1.18
+
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// causerOnly implements only Cause and not Unwrap
+type causerOnly struct {
+	cause error
+}
+
+func (e causerOnly) Error() string { return "causer: " + e.cause.Error() }
+func (e causerOnly) Cause() error  { return e.cause }
+
+// asCodeError matches *codeError via its As method
+type asCodeError struct{}
+
+func (asCodeError) Error() string { return "as code error" }
+
+func (asCodeError) As(target interface{}) bool {
+	if t, ok := target.(**codeError); ok {
+		*t = &codeError{99}
+		return true
+	}
+	return false
+}
+
+func Test_AsType(t *testing.T) {
+	pathErr := &os.PathError{Op: "open", Path: "x", Err: io.EOF}
+
+	e, ok := AsType[*os.PathError](Wrap(pathErr, "wrapped"))
+	assert.True(t, ok)
+	assert.Same(t, pathErr, e)
+
+	_, ok = AsType[*os.PathError](nil)
+	assert.False(t, ok)
+
+	_, ok = AsType[*os.LinkError](Wrap(pathErr, "wrapped"))
+	assert.False(t, ok)
+
+	// Not reachable for errors.As
+	err := causerOnly{Combine(New("e0"), fmt.Errorf("e1: %w", pathErr))}
+	assert.False(t, errors.As(err, new(*os.PathError)))
+	e, ok = AsType[*os.PathError](err)
+	assert.True(t, ok)
+	assert.Same(t, pathErr, e)
+
+	// Interface types
+	combined := Combine(Const("e0"), Const("e1"))
+	multi, ok := AsType[interface{ Errors() []error }](Wrap(combined, "wrapped"))
+	assert.True(t, ok)
+	assert.Equal(t, combined, multi)
+
+	// As methods
+	codeErr, ok := AsType[*codeError](Wrap(asCodeError{}, "wrapped"))
+	assert.True(t, ok)
+	assert.Equal(t, 99, codeErr.code)
+}
+
+func Test_FindAll(t *testing.T) {
+	e0 := &codeError{0}
+	e1 := &codeError{1}
+	e2 := &codeError{2}
+
+	err := Combine(
+		Wrap(e0, "e0"),
+		errors.Join(New("x"), causerOnly{e1}),
+		Combine(fmt.Errorf("%w", e2), asCodeError{}),
+	)
+	codeErrs := FindAll[*codeError](err)
+	assert.Len(t, codeErrs, 4)
+	assert.Equal(t, []*codeError{e0, e1, e2, {99}}, codeErrs)
+
+	assert.Nil(t, FindAll[*fs.PathError](err))
+	assert.Nil(t, FindAll[*codeError](nil))
+}
+
+func Test_HasType(t *testing.T) {
+	err := Combine(New("e0"), causerOnly{&codeError{1}})
+	assert.True(t, HasType[*codeError](err))
+	assert.False(t, HasType[*os.PathError](err))
+	assert.False(t, HasType[*codeError](nil))
+}
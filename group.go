@@ -0,0 +1,153 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Group runs functions in goroutines and collects
+// the errors they return in its embedded Collection.
+// In contrast to golang.org/x/sync/errgroup all errors are kept,
+// and panics of the functions are recovered as errors.
+// The zero value is a valid Group without a context,
+// concurrency limit, or cancellation.
+// Example:
+//     g, ctx := errors.NewGroup(ctx)
+//     g.SetLimit(8)
+//     for _, doc := range docs {
+//         doc := doc
+//         g.Go(func() error { return process(ctx, doc) })
+//     }
+//     return g.Wait()
+type Group struct {
+	Collection
+
+	wg          sync.WaitGroup
+	sem         chan struct{}
+	cancel      context.CancelFunc
+	cancelAfter int
+	failed      int64
+}
+
+// NewGroup returns a new Group and a context derived from ctx
+// that is canceled when the first function returns an error
+// or panics, or when Wait returns.
+// Use SetCancelAfter to cancel after more errors.
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{cancel: cancel, cancelAfter: 1}, ctx
+}
+
+// SetLimit limits the number of functions running concurrently to n.
+// Go blocks until a running function returned if the limit is reached.
+// A limit less than one removes the limit.
+// SetLimit must not be called while functions are running.
+func (g *Group) SetLimit(n int) {
+	if len(g.sem) != 0 {
+		panic(fmt.Errorf("errors: SetLimit called while %d functions are running", len(g.sem)))
+	}
+	if n < 1 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// SetCancelAfter sets the number of errors after which
+// the context returned by NewGroup is canceled.
+// The context is only canceled by Wait if n is less than one.
+// SetCancelAfter must not be called while functions are running.
+func (g *Group) SetCancelAfter(n int) {
+	g.cancelAfter = n
+}
+
+// Go calls f in a new goroutine and adds the returned error
+// or the recovered panic of f as error with a stack trace
+// to the errors of the Group.
+func (g *Group) Go(f func() error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.wg.Add(1)
+	go func() {
+		defer func() {
+			if g.sem != nil {
+				<-g.sem
+			}
+			g.wg.Done()
+		}()
+
+		g.fail(recoverError(f))
+	}()
+}
+
+// Wait blocks until all functions called with Go returned
+// and then returns their errors combined like by Collection.Combine,
+// or nil if no function failed.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel()
+	}
+
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+
+	return g.combine(1)
+}
+
+func (g *Group) fail(err error) {
+	if err == nil {
+		return
+	}
+	g.Add(err)
+	failed := atomic.AddInt64(&g.failed, 1)
+	if g.cancel != nil && g.cancelAfter > 0 && failed >= int64(g.cancelAfter) {
+		g.cancel()
+	}
+}
+
+// recoverError returns the result of f
+// or a recovered panic of f as error
+// with the stack trace of the panic.
+func recoverError(f func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			policy := GetStackPolicy()
+			if policy.Mode != StackNever {
+				// Capture the complete stack of the panic
+				// even if the recovered error has a stack trace
+				policy.Mode = StackAlways
+			}
+			cause := AsError(p)
+			err = &withStack{
+				&withMessage{cause: cause, msg: "panic"},
+				policy.callers(0, cause),
+			}
+		}
+	}()
+
+	return f()
+}
+
+// AsError converts val to an error,
+// for example a value recovered from a panic.
+// It returns nil if val is nil,
+// val if it is an error, and otherwise an error
+// with the string representation of val as message.
+func AsError(val interface{}) error {
+	switch x := val.(type) {
+	case nil:
+		return nil
+	case error:
+		return x
+	case string:
+		return errors.New(x)
+	case fmt.Stringer:
+		return errors.New(x.String())
+	}
+	return errors.New(fmt.Sprintf("%+v", val))
+}
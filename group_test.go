@@ -0,0 +1,139 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Group(t *testing.T) {
+	var g Group
+	assert.NoError(t, g.Wait())
+
+	for i := 0; i < 10; i++ {
+		i := i
+		g.Go(func() error {
+			if i%3 == 0 {
+				return fmt.Errorf("error %d", i)
+			}
+			return nil
+		})
+	}
+	err := g.Wait()
+	assert.Error(t, err)
+	assert.Len(t, Uncombine(err), 4)
+	for _, i := range []int{0, 3, 6, 9} {
+		assert.Contains(t, err.Error(), fmt.Sprintf("error %d", i))
+	}
+}
+
+func Test_GroupPanic(t *testing.T) {
+	var g Group
+	g.Go(func() error { panic("boom") })
+	g.Go(func() error { panic(Const("const")) })
+	g.Go(func() error { return nil })
+
+	err := g.Wait()
+	assert.Len(t, Uncombine(err), 2)
+	assert.Contains(t, err.Error(), "panic: boom")
+	assert.Contains(t, err.Error(), "panic: const")
+	assert.ErrorIs(t, err, Const("const"))
+
+	// The stack trace starts at the panic
+	var boom error
+	for _, e := range Uncombine(err) {
+		if strings.Contains(e.Error(), "boom") {
+			boom = e
+		}
+	}
+	assert.Contains(t, fmt.Sprintf("%+v", boom), "Test_GroupPanic.func1")
+
+	// Also for panics with errors that already have a stack trace
+	stacked := New("stacked")
+	g.Go(func() error { return panicWith(stacked) })
+	err = g.Wait()
+	assert.Contains(t, fmt.Sprintf("%+v", err), "errors.panicWith")
+}
+
+func panicWith(err error) error {
+	panic(err)
+}
+
+func Test_GroupLimit(t *testing.T) {
+	var (
+		g       Group
+		running int64
+		max     int64
+	)
+	g.SetLimit(2)
+	for i := 0; i < 20; i++ {
+		g.Go(func() error {
+			n := atomic.AddInt64(&running, 1)
+			defer atomic.AddInt64(&running, -1)
+			for {
+				m := atomic.LoadInt64(&max)
+				if n <= m || atomic.CompareAndSwapInt64(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+	}
+	assert.NoError(t, g.Wait())
+	assert.True(t, atomic.LoadInt64(&max) <= 2, "max concurrency %d", max)
+}
+
+func Test_GroupCancel(t *testing.T) {
+	g, ctx := NewGroup(context.Background())
+	g.Go(func() error { return Const("first") })
+	g.Go(func() error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	err := g.Wait()
+	assert.Len(t, Uncombine(err), 2)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	g, ctx = NewGroup(context.Background())
+	g.SetCancelAfter(3)
+	g.SetLimit(1)
+	for i := 0; i < 2; i++ {
+		g.Go(func() error { return Const("error") })
+	}
+	g.Go(func() error { return ctx.Err() })
+	err = g.Wait()
+	assert.Len(t, Uncombine(err), 2)
+	assert.Error(t, ctx.Err(), "canceled by Wait")
+}
+
+func Test_AsError(t *testing.T) {
+	assert.NoError(t, AsError(nil))
+	assert.Equal(t, Const("const"), AsError(Const("const")))
+	assert.EqualError(t, AsError("string"), "string")
+	assert.EqualError(t, AsError(time.Second), "1s")
+	assert.EqualError(t, AsError(42), "42")
+}
+
+func Test_GroupWaitStack(t *testing.T) {
+	type stackTracer interface {
+		StackTrace() StackTrace
+	}
+
+	var g Group
+	g.Go(func() error { return Const("e0") })
+	g.Go(func() error { return Const("e1") })
+	err := g.Wait()
+	assert.Equal(t, "github.com/domonda/errors.Test_GroupWaitStack", err.(stackTracer).StackTrace()[0].name())
+
+	var c Collection
+	c.Add(Const("e0"))
+	c.Add(Const("e1"))
+	assert.Equal(t, "github.com/domonda/errors.Test_GroupWaitStack", c.Combine().(stackTracer).StackTrace()[0].name())
+	assert.Equal(t, "github.com/domonda/errors.Test_GroupWaitStack", c.TakeCombined().(stackTracer).StackTrace()[0].name())
+}
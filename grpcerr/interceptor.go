@@ -0,0 +1,105 @@
+package grpcerr
+
+import (
+	"context"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor converts the errors returned by
+// the handlers of a server to gRPC statuses with Status.
+// gRPC status errors, like errors of calls to other services,
+// are returned unchanged if they are not wrapped,
+// but without the internal details of statuses converted
+// with the Debug option if it is not set for this server.
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	return resp, toStatusError(err)
+}
+
+// StreamServerInterceptor converts the errors returned by
+// the stream handlers of a server to gRPC statuses with Status.
+// gRPC status errors that are not wrapped
+// are returned unchanged.
+func StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return toStatusError(handler(srv, ss))
+}
+
+// UnaryClientInterceptor reconstructs the errors
+// returned by calls of a client with FromError.
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return FromError(invoker(ctx, method, req, reply, cc, opts...))
+}
+
+// StreamClientInterceptor reconstructs the errors
+// returned by streaming calls of a client
+// and by the methods of their streams with FromError.
+func StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, FromError(err)
+	}
+	return clientStream{stream}, nil
+}
+
+// clientStream reconstructs the errors of a grpc.ClientStream.
+// Errors without gRPC status like io.EOF are returned unchanged.
+type clientStream struct {
+	grpc.ClientStream
+}
+
+func (s clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	return md, FromError(err)
+}
+
+func (s clientStream) CloseSend() error {
+	return FromError(s.ClientStream.CloseSend())
+}
+
+func (s clientStream) SendMsg(m interface{}) error {
+	return FromError(s.ClientStream.SendMsg(m))
+}
+
+func (s clientStream) RecvMsg(m interface{}) error {
+	return FromError(s.ClientStream.RecvMsg(m))
+}
+
+func toStatusError(err error) error {
+	type grpcStatuser interface {
+		GRPCStatus() *status.Status
+	}
+
+	if err == nil {
+		return nil
+	}
+	o := GetOptions()
+	// Only status errors that are not wrapped are passed on unchanged,
+	// wrapped status errors can have other codes and public messages
+	// or be combined with other errors, so they are converted
+	// with Status which uses the inner status at the correct depth
+	if statuser, ok := err.(grpcStatuser); ok {
+		if st := statuser.GRPCStatus(); st != nil && st.Code() != codes.OK {
+			if !o.Debug && hasDebugInfo(st) {
+				// Don't pass on the internal details of a status
+				// converted with the Debug option by another service
+				return StatusWithOptions(FromStatus(st), o).Err()
+			}
+			return st.Err()
+		}
+	}
+	return StatusWithOptions(err, o).Err()
+}
+
+func hasDebugInfo(st *status.Status) bool {
+	for _, detail := range st.Details() {
+		if _, ok := detail.(*errdetails.DebugInfo); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package grpcerr
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/domonda/errors"
+)
+
+const errUnavailable = errors.Const("database unavailable")
+
+// healthServer fails every check with err
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	err error
+}
+
+func (s *healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	return nil, s.err
+}
+
+func (s *healthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	return s.err
+}
+
+func newTestClient(t *testing.T, err error) grpc_health_v1.HealthClient {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor),
+		grpc.StreamInterceptor(StreamServerInterceptor),
+	)
+	grpc_health_v1.RegisterHealthServer(server, &healthServer{err: err})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, e := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor),
+		grpc.WithStreamInterceptor(StreamClientInterceptor),
+	)
+	if e != nil {
+		t.Fatal(e)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func Test_Interceptors(t *testing.T) {
+	defer SetOptions(SetOptions(Options{Debug: true}))
+
+	serverErr := WithGRPCCode(errors.Wrap(errUnavailable, "checking health"), codes.Unavailable)
+	client := newTestClient(t, serverErr)
+
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.EqualError(t, err, "checking health: database unavailable")
+	assert.True(t, stderrors.Is(err, errUnavailable))
+	assert.Equal(t, codes.Unavailable, Code(err))
+
+	// Code checks of the grpc package keep working
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	st, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, "checking health: database unavailable", st.Message())
+
+	// The stack trace of the server is kept
+	assert.Contains(t, fmt.Sprintf("%+v", err), "grpcerr.Test_Interceptors\n")
+
+	// Streams
+	stream, err := client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.EqualError(t, err, "checking health: database unavailable")
+	assert.True(t, stderrors.Is(err, errUnavailable))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func Test_InterceptorsPublic(t *testing.T) {
+	serverErr := errors.WithPublicMessage(errors.Wrap(errUnavailable, "SELECT 1"), "Try again later")
+	client := newTestClient(t, WithGRPCCode(serverErr, codes.Unavailable))
+
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, "Try again later", status.Convert(err).Message())
+	assert.NotContains(t, fmt.Sprintf("%+v", err), "SELECT")
+}
+
+func Test_InterceptorsStatusError(t *testing.T) {
+	client := newTestClient(t, status.Error(codes.PermissionDenied, "denied"))
+
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, codes.PermissionDenied, Code(err))
+	assert.Equal(t, "denied", status.Convert(err).Message())
+
+	// Wrapped status errors of calls to other services keep their code
+	client = newTestClient(t, errors.Wrap(status.Error(codes.PermissionDenied, "denied"), "calling downstream"))
+	_, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, errors.GetDefaultPublicMessage(), status.Convert(err).Message())
+
+	// but not their debug information
+	downstream := StatusWithOptions(errors.WithPublicMessage(errors.New("ACL table corrupt"), "Not allowed"), Options{Debug: true})
+	client = newTestClient(t, errors.Wrap(FromStatus(downstream), "calling downstream"))
+	_, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.Equal(t, codes.Unknown, status.Code(err))
+	assert.Equal(t, "Not allowed", status.Convert(err).Message())
+	assert.False(t, hasDebugInfo(status.Convert(err)))
+}
+
+func Test_InterceptorsWrappedStatusError(t *testing.T) {
+	downstream := FromStatus(StatusWithOptions(WithGRPCCode(errors.New("connection refused"), codes.Unavailable), Options{Debug: true}))
+
+	// The outer code and public message take precedence over the downstream status
+	serverErr := errors.WithPublicMessage(WithGRPCCode(errors.Wrap(downstream, "calling X"), codes.Internal), "public")
+	client := newTestClient(t, serverErr)
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "public", status.Convert(err).Message())
+	assert.False(t, hasDebugInfo(status.Convert(err)))
+
+	// Combined errors are not reduced to the downstream status
+	defer SetOptions(SetOptions(Options{Debug: true}))
+	client = newTestClient(t, errors.Combine(downstream, errUnavailable))
+	_, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.True(t, stderrors.Is(err, errUnavailable))
+	assert.Contains(t, err.Error(), "connection refused")
+}
+
+func Test_InterceptorsCodeOK(t *testing.T) {
+	client := newTestClient(t, WithGRPCCode(io.EOF, codes.OK))
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.Equal(t, codes.Unknown, status.Code(err))
+
+	client = newTestClient(t, errors.WithCode(io.EOF, codes.OK))
+	_, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.Equal(t, codes.Unknown, status.Code(err))
+}
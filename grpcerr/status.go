@@ -0,0 +1,457 @@
+// Package grpcerr converts errors to gRPC statuses and back.
+//
+// Status converts an error to a gRPC status with the code
+// attached by WithGRPCCode, returned by a GRPCStatus method,
+// or registered with RegisterCode for sentinel errors.
+// The message of the status is the public message of the error
+// returned by errors.PublicMessage, so that no internal details
+// are sent to clients.
+//
+// For trusted clients like other internal services
+// the Debug option attaches the complete error chain with messages,
+// fields, and stack traces as errdetails.DebugInfo,
+// so that FromStatus can reconstruct the error on the client side
+// with errors.FromJSON.
+// errors.Is then works for Const sentinels shared by client and server.
+//
+// The interceptors of this package do the conversion
+// for all calls of a server or client:
+//
+//     grpcerr.SetOptions(grpcerr.Options{Debug: true})
+//     server := grpc.NewServer(
+//             grpc.UnaryInterceptor(grpcerr.UnaryServerInterceptor),
+//             grpc.StreamInterceptor(grpcerr.StreamServerInterceptor),
+//     )
+//     conn, err := grpc.NewClient(target,
+//             grpc.WithUnaryInterceptor(grpcerr.UnaryClientInterceptor),
+//             grpc.WithStreamInterceptor(grpcerr.StreamClientInterceptor),
+//     )
+package grpcerr
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/domonda/errors"
+)
+
+// Domain is the domain of the errdetails.ErrorInfo attached by Status.
+// FromStatus only reconstructs errors from statuses with this domain.
+const Domain = "github.com/domonda/errors"
+
+// WithGRPCCode annotates err with a gRPC code
+// without changing the code returned by errors.Code.
+// The Error method of the returned error returns err.Error() unchanged,
+// the code can be retrieved with the Code function.
+// If err is nil, WithGRPCCode returns nil.
+func WithGRPCCode(err error, code codes.Code) error {
+	if err == nil {
+		return nil
+	}
+	return &withGRPCCode{
+		cause: err,
+		code:  code,
+	}
+}
+
+type withGRPCCode struct {
+	cause error
+	code  codes.Code
+}
+
+func (w *withGRPCCode) Error() string {
+	return w.cause.Error()
+}
+
+func (w *withGRPCCode) Cause() error {
+	return w.cause
+}
+
+func (w *withGRPCCode) Unwrap() error {
+	return w.cause
+}
+
+func (w *withGRPCCode) GRPCCode() codes.Code {
+	return w.code
+}
+
+func (w *withGRPCCode) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%+v\ngrpc=%s", w.Cause(), w.code)
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, w.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", w.Error())
+	}
+}
+
+// statusError is an error reconstructed by FromStatus
+// that returns the received status from its GRPCStatus method
+// like the errors of the grpc package.
+type statusError struct {
+	cause  error
+	status *status.Status
+}
+
+func (e *statusError) Error() string {
+	return e.cause.Error()
+}
+
+func (e *statusError) Cause() error {
+	return e.cause
+}
+
+func (e *statusError) Unwrap() error {
+	return e.cause
+}
+
+func (e *statusError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+func (e *statusError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%+v\ngrpc=%s", e.Cause(), e.status.Code())
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+// Options configures how Status converts errors to gRPC statuses.
+type Options struct {
+	// Debug uses err.Error() instead of the public message
+	// of the error as status message and attaches the fields
+	// of the error as metadata of the errdetails.ErrorInfo,
+	// and the complete error chain with stack traces as errdetails.DebugInfo.
+	// Debug exposes internal details and must only be used
+	// for trusted clients.
+	Debug bool
+}
+
+var options atomic.Value // Options
+
+// SetOptions sets the package level Options used by Status
+// and the server interceptors, and returns the previous ones.
+func SetOptions(o Options) (previous Options) {
+	previous = GetOptions()
+	options.Store(o)
+	return previous
+}
+
+// GetOptions returns the package level Options used by Status.
+func GetOptions() Options {
+	o, _ := options.Load().(Options)
+	return o
+}
+
+type registeredCode struct {
+	target error
+	code   codes.Code
+}
+
+var (
+	registeredCodes    []registeredCode
+	registeredCodesMtx sync.RWMutex
+)
+
+// RegisterCode registers the gRPC code for errors
+// for which errors.Is returns true for target,
+// like sentinel errors that are wrapped.
+// Codes attached with WithGRPCCode take precedence
+// over registered codes, and codes registered earlier
+// take precedence over codes registered later.
+func RegisterCode(target error, code codes.Code) {
+	registeredCodesMtx.Lock()
+	defer registeredCodesMtx.Unlock()
+
+	registeredCodes = append(registeredCodes, registeredCode{target, code})
+}
+
+// UnregisterCode removes the codes registered
+// with RegisterCode for target.
+func UnregisterCode(target error) {
+	registeredCodesMtx.Lock()
+	defer registeredCodesMtx.Unlock()
+
+	kept := registeredCodes[:0:0]
+	for _, r := range registeredCodes {
+		if !sameTarget(r.target, target) {
+			kept = append(kept, r)
+		}
+	}
+	registeredCodes = kept
+}
+
+// sameTarget compares registered targets with errors.Is
+// which doesn't panic for not comparable types,
+// and targets of not comparable types that errors.Is
+// can't compare by the data they point to.
+func sameTarget(a, b error) bool {
+	if stderrors.Is(a, b) {
+		return true
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() || va.Type() != vb.Type() || va.Type().Comparable() {
+		return false
+	}
+	switch va.Kind() {
+	case reflect.Map, reflect.Func:
+		return va.Pointer() == vb.Pointer()
+	case reflect.Slice:
+		return va.Pointer() == vb.Pointer() && va.Len() == vb.Len()
+	}
+	return false
+}
+
+func registeredCodeOf(err error) (codes.Code, bool) {
+	registeredCodesMtx.RLock()
+	defer registeredCodesMtx.RUnlock()
+
+	for _, r := range registeredCodes {
+		if stderrors.Is(err, r.target) {
+			return r.code, true
+		}
+	}
+	return codes.Unknown, false
+}
+
+// Code returns the gRPC code of err.
+// The outermost code in the tree of causes and combined errors
+// attached with WithGRPCCode, attached as codes.Code with errors.WithCode,
+// or returned by a GRPCStatus method
+// is returned, else the code registered with RegisterCode for
+// the first matching sentinel error, or codes.Canceled and
+// codes.DeadlineExceeded for the corresponding context errors.
+// If err is nil, then codes.OK is returned,
+// and if no code was found, then codes.Unknown.
+func Code(err error) codes.Code {
+	type grpcCoder interface {
+		GRPCCode() codes.Code
+	}
+	type coder interface {
+		Code() interface{}
+	}
+	type grpcStatuser interface {
+		GRPCStatus() *status.Status
+	}
+
+	if err == nil {
+		return codes.OK
+	}
+
+	code, codeDepth := codes.Unknown, -1
+	errors.Walk(err, func(e error, depth int) bool {
+		if codeDepth != -1 && depth >= codeDepth {
+			return false
+		}
+		switch x := e.(type) {
+		case grpcCoder:
+			code, codeDepth = x.GRPCCode(), depth
+			return false
+		case coder:
+			if c, ok := x.Code().(codes.Code); ok {
+				code, codeDepth = c, depth
+				return false
+			}
+		case grpcStatuser:
+			if st := x.GRPCStatus(); st != nil {
+				code, codeDepth = st.Code(), depth
+				return false
+			}
+		}
+		return true
+	})
+	if codeDepth != -1 {
+		return code
+	}
+
+	if c, ok := registeredCodeOf(err); ok {
+		return c
+	}
+	switch {
+	case stderrors.Is(err, context.Canceled):
+		return codes.Canceled
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	}
+	return codes.Unknown
+}
+
+// Status returns a gRPC status for err
+// converted with the package level Options, see StatusWithOptions.
+func Status(err error) *status.Status {
+	return StatusWithOptions(err, GetOptions())
+}
+
+// StatusWithOptions returns a gRPC status for err with the code
+// returned by Code and the public message of err returned by
+// errors.PublicMessage as message.
+// An errdetails.ErrorInfo with the code as reason is attached as detail.
+// If the Debug option is set, then err.Error() is used as message,
+// the fields of err are attached as metadata of the errdetails.ErrorInfo,
+// and an errdetails.DebugInfo with the JSON of the error chain
+// returned by errors.MarshalChain and the innermost stack trace
+// is attached as detail.
+// If err is nil, then a status with codes.OK is returned,
+// and codes.OK attached to an error is replaced by codes.Unknown
+// because a status with codes.OK reports success.
+func StatusWithOptions(err error, o Options) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+
+	code := Code(err)
+	if code == codes.OK {
+		code = codes.Unknown
+	}
+	info := &errdetails.ErrorInfo{
+		Reason: reason(code),
+		Domain: Domain,
+	}
+	if !o.Debug {
+		st, e := status.New(code, errors.PublicMessage(err)).WithDetails(info)
+		if e != nil {
+			return status.New(code, errors.PublicMessage(err))
+		}
+		return st
+	}
+
+	st := status.New(code, err.Error())
+	info.Metadata = fieldStrings(errors.Fields(err))
+	debug := &errdetails.DebugInfo{
+		StackEntries: stackEntries(err),
+	}
+	if chain, e := errors.MarshalChain(err); e == nil {
+		debug.Detail = string(chain)
+	}
+	withDetails, e := st.WithDetails(info, debug)
+	if e != nil {
+		// Details are optional, the code and message
+		// are the important information
+		return st
+	}
+	return withDetails
+}
+
+// FromStatus reconstructs the error that was converted to st
+// by Status with the Debug option with errors.FromJSON.
+// The returned error returns st from its GRPCStatus method,
+// so functions like status.Code and status.FromError
+// of the grpc package keep working with it.
+// If st has no error chain, then st.Err() is returned.
+// If st is nil or has codes.OK, then nil is returned.
+func FromStatus(st *status.Status) error {
+	if st == nil || st.Code() == codes.OK {
+		return nil
+	}
+
+	var (
+		fromDomain bool
+		chain      string
+	)
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			fromDomain = d.Domain == Domain
+		case *errdetails.DebugInfo:
+			chain = d.Detail
+		}
+	}
+	if !fromDomain || chain == "" {
+		return st.Err()
+	}
+	remote, err := errors.FromJSON([]byte(chain))
+	if err != nil || remote == nil {
+		return st.Err()
+	}
+	return &statusError{
+		cause:  remote,
+		status: st,
+	}
+}
+
+// FromError reconstructs the error from the gRPC status
+// of err with FromStatus.
+// If err has no gRPC status, then err is returned unchanged.
+func FromError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return FromStatus(st)
+}
+
+// reason returns the name of code in upper snake case
+// as recommended for errdetails.ErrorInfo.Reason.
+func reason(code codes.Code) string {
+	var b strings.Builder
+	for i, r := range code.String() {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// fieldStrings returns the fields formatted as strings.
+func fieldStrings(fields map[string]interface{}) map[string]string {
+	if len(fields) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(fields))
+	for key, value := range fields {
+		m[key] = fmt.Sprint(value)
+	}
+	return m
+}
+
+// stackEntries returns the innermost stack trace
+// in the tree of err formatted like "function file:line".
+func stackEntries(err error) []string {
+	type resolvedStackTracer interface {
+		ResolvedStackTrace() errors.ResolvedStackTrace
+	}
+
+	var (
+		stack      errors.ResolvedStackTrace
+		stackDepth = -1
+	)
+	errors.Walk(err, func(e error, depth int) bool {
+		if s, ok := e.(resolvedStackTracer); ok && depth > stackDepth {
+			if st := s.ResolvedStackTrace(); len(st) > 0 {
+				stack, stackDepth = st, depth
+			}
+		}
+		return true
+	})
+
+	entries := make([]string, len(stack))
+	for i, frame := range stack {
+		entries[i] = fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line)
+	}
+	return entries
+}
@@ -0,0 +1,143 @@
+package grpcerr
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/domonda/errors"
+)
+
+const errNotFound = errors.Const("not found")
+
+func Test_Code(t *testing.T) {
+	assert.Equal(t, codes.OK, Code(nil))
+	assert.Equal(t, codes.Unknown, Code(io.EOF))
+	assert.Equal(t, codes.Canceled, Code(errors.Wrap(context.Canceled, "wrapped")))
+	assert.Equal(t, codes.DeadlineExceeded, Code(fmt.Errorf("wrapped: %w", context.DeadlineExceeded)))
+
+	err := WithGRPCCode(io.EOF, codes.InvalidArgument)
+	assert.Equal(t, codes.InvalidArgument, Code(errors.Wrap(err, "wrapped")))
+	assert.Equal(t, codes.PermissionDenied, Code(WithGRPCCode(errors.Wrap(err, "wrapped"), codes.PermissionDenied)))
+	assert.Equal(t, codes.Unavailable, Code(errors.Wrap(status.Error(codes.Unavailable, "down"), "wrapped")))
+
+	RegisterCode(errNotFound, codes.NotFound)
+	defer UnregisterCode(errNotFound)
+	assert.Equal(t, codes.NotFound, Code(errors.Wrap(errNotFound, "wrapped")))
+	assert.Equal(t, codes.Internal, Code(WithGRPCCode(errNotFound, codes.Internal)))
+	assert.Equal(t, codes.Aborted, Code(errors.WithCode(io.EOF, codes.Aborted)))
+
+	// The gRPC code doesn't hide the classification code
+	err = WithGRPCCode(errors.WithCode(io.EOF, "my-code"), codes.Aborted)
+	assert.Equal(t, "my-code", errors.Code(err))
+	assert.Equal(t, codes.Aborted, Code(err))
+	assert.Equal(t, "EOF\ncode=my-code\ngrpc=Aborted", fmt.Sprintf("%+v", err))
+
+	UnregisterCode(errNotFound)
+	assert.Equal(t, codes.Unknown, Code(errNotFound))
+}
+
+func Test_StatusRoundTrip(t *testing.T) {
+	original := errors.Wrap(errors.WithField(errNotFound, "documentID", "abc"), "loading document")
+	original = WithGRPCCode(original, codes.NotFound)
+
+	st := StatusWithOptions(original, Options{Debug: true})
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "loading document: not found", st.Message())
+	assert.Len(t, st.Details(), 2)
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+	assert.Equal(t, "NOT_FOUND", info.Reason)
+	assert.Equal(t, Domain, info.Domain)
+	assert.Equal(t, map[string]string{"documentID": "abc"}, info.Metadata)
+	debug := st.Details()[1].(*errdetails.DebugInfo)
+	assert.Contains(t, debug.StackEntries[0], "grpcerr.Test_StatusRoundTrip")
+
+	err := FromStatus(st)
+	assert.EqualError(t, err, "loading document: not found")
+	assert.True(t, stderrors.Is(err, errNotFound))
+	assert.Equal(t, errNotFound, errors.Cause(err))
+	assert.Equal(t, codes.NotFound, Code(err))
+	assert.Equal(t, map[string]interface{}{"documentID": "abc"}, errors.Fields(err))
+
+	// The received status is kept
+	assert.Equal(t, st, status.Convert(err))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, codes.NotFound, status.Code(errors.Wrap(err, "wrapped")))
+
+	// Converting again keeps the code
+	assert.Equal(t, codes.NotFound, Status(err).Code())
+}
+
+func Test_StatusPublic(t *testing.T) {
+	err := errors.WithField(errors.Wrap(errNotFound, "SELECT * FROM document"), "documentID", "abc")
+	err = WithGRPCCode(errors.WithPublicMessage(err, "Document not found"), codes.NotFound)
+
+	st := Status(err)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "Document not found", st.Message())
+	assert.Len(t, st.Details(), 1)
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+	assert.Equal(t, "NOT_FOUND", info.Reason)
+	assert.Empty(t, info.Metadata)
+
+	// Without a chain the status error is returned
+	received := FromStatus(st)
+	assert.EqualError(t, received, "rpc error: code = NotFound desc = Document not found")
+	assert.Equal(t, codes.NotFound, Code(received))
+
+	assert.Equal(t, errors.DefaultPublicMessage, Status(io.EOF).Message())
+
+	defer SetOptions(SetOptions(Options{Debug: true}))
+	assert.Equal(t, Options{Debug: true}, GetOptions())
+	assert.Equal(t, "SELECT * FROM document: not found", Status(err).Message())
+}
+
+func Test_FromStatus(t *testing.T) {
+	assert.NoError(t, FromStatus(nil))
+	assert.NoError(t, FromStatus(status.New(codes.OK, "")))
+	assert.NoError(t, FromError(nil))
+	assert.Equal(t, io.EOF, FromError(io.EOF))
+
+	// Statuses not created by Status
+	st := status.New(codes.Unavailable, "down")
+	err := FromStatus(st)
+	assert.EqualError(t, err, "rpc error: code = Unavailable desc = down")
+	assert.Equal(t, codes.Unavailable, Code(err))
+
+	assert.Equal(t, codes.OK, Status(nil).Code())
+}
+
+type sliceError []string
+
+func (e sliceError) Error() string { return "slice error" }
+
+func Test_UnregisterCodeNotComparable(t *testing.T) {
+	target := sliceError{"a"}
+	RegisterCode(target, codes.NotFound)
+	assert.NotPanics(t, func() { UnregisterCode(sliceError{"b"}) })
+	assert.NotPanics(t, func() { UnregisterCode(io.EOF) })
+	assert.Equal(t, codes.NotFound, registeredCodeOfTarget(target))
+	assert.NotPanics(t, func() { UnregisterCode(target) })
+	assert.Equal(t, codes.Unknown, registeredCodeOfTarget(target))
+}
+
+// registeredCodeOfTarget returns the code registered for target
+// which errors.Is can't find for not comparable targets.
+func registeredCodeOfTarget(target error) codes.Code {
+	registeredCodesMtx.RLock()
+	defer registeredCodesMtx.RUnlock()
+
+	for _, r := range registeredCodes {
+		if sameTarget(r.target, target) {
+			return r.code
+		}
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,101 @@
+package httperr
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/domonda/errors"
+)
+
+// ContentType is the media type of RFC 7807 problem details.
+const ContentType = "application/problem+json"
+
+// Problem holds the RFC 7807 problem details of an error.
+type Problem struct {
+	// Type is a URI reference identifying the problem type,
+	// "about:blank" is implied if empty.
+	Type string `json:"type,omitempty"`
+
+	// Title is the status text of the HTTP status.
+	Title string `json:"title"`
+
+	// Status is the HTTP status code.
+	Status int `json:"status"`
+
+	// Detail is the public message of the error
+	// that is safe to be shown to clients.
+	Detail string `json:"detail,omitempty"`
+
+	// Instance is the path of the request URL.
+	Instance string `json:"instance,omitempty"`
+}
+
+// NewProblem returns the Problem for err with the status
+// returned by Status and the public message of err
+// returned by errors.PublicMessage as Detail,
+// which is empty instead of the generic default message
+// if err has no public message.
+// The message returned by the Error method of err is
+// never used because it can contain internal details.
+// A public message is attached with errors.WithPublicMessage
+// or returned by errors implementing the following interface
+// in the tree of causes and combined errors:
+//
+//     type publicMessager interface {
+//             PublicMessage() string
+//     }
+//
+// If r is not nil, then the path of its URL is used as Instance.
+// If err is nil, then nil is returned.
+func NewProblem(r *http.Request, err error) *Problem {
+	if err == nil {
+		return nil
+	}
+	status := Status(err)
+	p := &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+	}
+	if errors.HasPublicMessage(err) {
+		// Detail is optional, so the generic
+		// default public message is not used
+		p.Detail = errors.PublicMessage(err)
+	}
+	if p.Title == "" {
+		p.Title = "Status " + strconv.Itoa(status)
+	}
+	if r != nil && r.URL != nil {
+		p.Instance = r.URL.Path
+	}
+	return p
+}
+
+// WriteResponse writes the problem as JSON response with
+// the Content-Type application/problem+json
+// and the status of the problem, or 500 if the status is invalid.
+func (p *Problem) WriteResponse(w http.ResponseWriter) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(validStatus(p.Status))
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
+
+// WriteProblem writes the Problem for err as response,
+// see NewProblem and Problem.WriteResponse.
+// The error is not logged, callers should log it
+// with %+v for the internal details.
+// If err is nil, then nothing is written.
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	if err == nil {
+		return
+	}
+	// Nothing can be done about write errors
+	// when the status was already sent
+	_ = NewProblem(r, err).WriteResponse(w)
+}
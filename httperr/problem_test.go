@@ -0,0 +1,85 @@
+package httperr
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/domonda/errors"
+)
+
+// publicError has a message that is safe for clients
+type publicError struct {
+	error
+	public string
+}
+
+func (e publicError) PublicMessage() string { return e.public }
+func (e publicError) Unwrap() error         { return e.error }
+
+func Test_WriteProblem(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := errors.Wrap(io.ErrUnexpectedEOF, "reading secret.json")
+		err = WithHTTPStatus(publicError{err, "Invalid document"}, http.StatusBadRequest)
+		WriteProblem(w, r, errors.Wrap(err, "handler"))
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/documents/123?token=x", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.NotContains(t, w.Body.String(), "secret")
+
+	var problem Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, Problem{
+		Title:    "Bad Request",
+		Status:   http.StatusBadRequest,
+		Detail:   "Invalid document",
+		Instance: "/documents/123",
+	}, problem)
+}
+
+func Test_NewProblem(t *testing.T) {
+	p := NewProblem(nil, io.EOF)
+	assert.Equal(t, &Problem{Title: "Internal Server Error", Status: http.StatusInternalServerError}, p)
+
+	p = NewProblem(nil, errors.Wrap(errors.WithPublicMessage(io.EOF, "Try again"), "internal"))
+	assert.Equal(t, "Try again", p.Detail)
+
+	p = NewProblem(nil, WithHTTPStatus(io.EOF, 599))
+	assert.Equal(t, "Status 599", p.Title)
+
+	w := httptest.NewRecorder()
+	assert.NoError(t, p.WriteResponse(w))
+	assert.Equal(t, 599, w.Code)
+	assert.JSONEq(t, `{"title":"Status 599","status":599}`, w.Body.String())
+
+	// Invalid statuses must not panic in net/http
+	p = NewProblem(nil, WithHTTPStatus(io.EOF, 42))
+	assert.Equal(t, http.StatusInternalServerError, p.Status)
+	w = httptest.NewRecorder()
+	WriteProblem(w, nil, WithHTTPStatus(io.EOF, 42))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// The public message with the least wrapping depth is used
+	e := io.EOF
+	err := errors.Combine(
+		errors.Wrap(errors.WithPublicMessage(e, "deeper"), "w"),
+		errors.WithPublicMessage(e, "outer"),
+	)
+	assert.Equal(t, errors.PublicMessage(err), NewProblem(nil, err).Detail)
+	assert.Equal(t, "outer", NewProblem(nil, err).Detail)
+
+	assert.Nil(t, NewProblem(nil, nil))
+	w = httptest.NewRecorder()
+	WriteProblem(w, nil, nil)
+	assert.Equal(t, 0, w.Body.Len())
+}
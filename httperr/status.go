@@ -0,0 +1,205 @@
+// Package httperr maps errors to HTTP statuses and writes
+// RFC 7807 application/problem+json responses for them.
+//
+// Errors are annotated with a status by WithHTTPStatus
+// that is resolved by Status through any wrapping and combining
+// of the error. The response written by WriteProblem only contains
+// the status and a public message, so the error itself
+// should still be logged with %+v for the internal details:
+//
+//     func handler(w http.ResponseWriter, r *http.Request) {
+//             err := serve(w, r)
+//             if err != nil {
+//                     log.Printf("%+v", err)
+//                     httperr.WriteProblem(w, r, err)
+//             }
+//     }
+package httperr
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/domonda/errors"
+)
+
+// StatusCode is an HTTP status code that can be used
+// as code with errors.WithCode if the HTTP status
+// should also be the classification code of an error.
+type StatusCode int
+
+// HTTPStatus returns the status code as int.
+func (s StatusCode) HTTPStatus() int { return int(s) }
+
+// WithHTTPStatus annotates err with an HTTP status code
+// without changing the code returned by errors.Code.
+// The Error method of the returned error returns err.Error() unchanged,
+// the status can be retrieved with the Status function.
+// Invalid statuses outside of the range 100 to 599
+// are replaced by http.StatusInternalServerError.
+// If err is nil, WithHTTPStatus returns nil.
+func WithHTTPStatus(err error, status int) error {
+	if err == nil {
+		return nil
+	}
+	return &withStatus{
+		cause:  err,
+		status: validStatus(status),
+	}
+}
+
+type withStatus struct {
+	cause  error
+	status int
+}
+
+func (w *withStatus) Error() string {
+	return w.cause.Error()
+}
+
+func (w *withStatus) Cause() error {
+	return w.cause
+}
+
+func (w *withStatus) Unwrap() error {
+	return w.cause
+}
+
+func (w *withStatus) HTTPStatus() int {
+	return w.status
+}
+
+func (w *withStatus) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%+v\nstatus=%d", w.Cause(), w.status)
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, w.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", w.Error())
+	}
+}
+
+// validStatus returns status if it is in the range
+// of valid HTTP statuses from 100 to 599
+// or else http.StatusInternalServerError.
+func validStatus(status int) int {
+	if status < 100 || status > 599 {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+// Policy selects the status of an error with multiple statuses
+// in its tree of causes and combined errors.
+type Policy int
+
+const (
+	// Outermost selects the status with the least wrapping depth,
+	// which is the one attached last.
+	// This is the default policy.
+	Outermost Policy = iota
+
+	// Innermost selects the status with the greatest wrapping depth,
+	// which is the one closest to the root cause.
+	Innermost
+
+	// Highest selects the highest status, so that for example
+	// a combination of a 404 and a 500 error results in 500.
+	Highest
+)
+
+// String returns the name of the policy.
+func (p Policy) String() string {
+	switch p {
+	case Outermost:
+		return "Outermost"
+	case Innermost:
+		return "Innermost"
+	case Highest:
+		return "Highest"
+	}
+	return fmt.Sprintf("Policy(%d)", int(p))
+}
+
+var policy int32 // Policy
+
+// SetPolicy sets the package level Policy used by Status
+// and returns the previous one.
+func SetPolicy(p Policy) (previous Policy) {
+	return Policy(atomic.SwapInt32(&policy, int32(p)))
+}
+
+// GetPolicy returns the package level Policy used by Status.
+func GetPolicy() Policy {
+	return Policy(atomic.LoadInt32(&policy))
+}
+
+// Status returns the HTTP status of err resolved
+// with the package level Policy, see StatusWithPolicy.
+func Status(err error) int {
+	return StatusWithPolicy(err, GetPolicy())
+}
+
+// StatusWithPolicy returns the HTTP status of err
+// resolved with the passed Policy from the statuses
+// attached with WithHTTPStatus to err and the errors
+// in its tree of causes and combined errors, or
+// from errors implementing the following interface:
+//
+//     type httpStatuser interface {
+//             HTTPStatus() int
+//     }
+//
+// If err is nil, then http.StatusOK is returned,
+// and if no status was found or the found status is not
+// in the range from 100 to 599, then http.StatusInternalServerError.
+func StatusWithPolicy(err error, p Policy) int {
+	if err == nil {
+		return http.StatusOK
+	}
+
+	status, statusDepth := 0, -1
+	errors.Walk(err, func(e error, depth int) bool {
+		s := httpStatus(e)
+		if s == 0 {
+			return true
+		}
+		switch {
+		case statusDepth == -1,
+			p == Outermost && depth < statusDepth,
+			p == Innermost && depth > statusDepth,
+			p == Highest && s > status:
+			status, statusDepth = s, depth
+		}
+		// No deeper status can be more outer
+		return p != Outermost
+	})
+	return validStatus(status)
+}
+
+// httpStatus returns the status of err itself or 0.
+func httpStatus(err error) int {
+	type httpStatuser interface {
+		HTTPStatus() int
+	}
+	type coder interface {
+		Code() interface{}
+	}
+
+	if s, ok := err.(httpStatuser); ok {
+		return s.HTTPStatus()
+	}
+	if c, ok := err.(coder); ok {
+		if s, ok := c.Code().(httpStatuser); ok {
+			return s.HTTPStatus()
+		}
+	}
+	return 0
+}
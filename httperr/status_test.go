@@ -0,0 +1,71 @@
+package httperr
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/domonda/errors"
+)
+
+// conflictError declares its status itself
+type conflictError string
+
+func (e conflictError) Error() string { return string(e) }
+func (conflictError) HTTPStatus() int { return http.StatusConflict }
+
+const errConflict conflictError = "conflict"
+
+func Test_Status(t *testing.T) {
+	assert.Equal(t, http.StatusOK, Status(nil))
+	assert.Equal(t, http.StatusInternalServerError, Status(io.EOF))
+
+	err := WithHTTPStatus(io.EOF, http.StatusNotFound)
+	assert.EqualError(t, err, "EOF")
+	assert.Equal(t, http.StatusNotFound, Status(err))
+	assert.Equal(t, http.StatusNotFound, Status(errors.Wrap(err, "wrapped")))
+	assert.Equal(t, http.StatusNotFound, Status(fmt.Errorf("wrapped: %w", err)))
+	assert.Equal(t, http.StatusConflict, Status(errors.Wrap(errConflict, "wrapped")))
+	assert.Equal(t, "EOF\nstatus=404", fmt.Sprintf("%+v", err))
+
+	// The status doesn't hide the classification code
+	err = WithHTTPStatus(errors.WithCode(io.EOF, "my-code"), http.StatusNotFound)
+	assert.Equal(t, "my-code", errors.Code(err))
+	assert.Equal(t, http.StatusNotFound, Status(err))
+
+	// Codes can be statuses
+	assert.Equal(t, http.StatusGone, Status(errors.WithCode(io.EOF, StatusCode(http.StatusGone))))
+
+	// Invalid statuses
+	assert.Equal(t, http.StatusInternalServerError, Status(WithHTTPStatus(io.EOF, 42)))
+	assert.Equal(t, http.StatusInternalServerError, Status(WithHTTPStatus(io.EOF, 600)))
+	assert.Equal(t, http.StatusInternalServerError, Status(errors.WithCode(io.EOF, StatusCode(1000))))
+	assert.Equal(t, http.StatusContinue, Status(WithHTTPStatus(io.EOF, http.StatusContinue)))
+	assert.NoError(t, WithHTTPStatus(nil, http.StatusNotFound))
+}
+
+func Test_StatusWithPolicy(t *testing.T) {
+	err := WithHTTPStatus(
+		errors.Wrap(WithHTTPStatus(io.EOF, http.StatusNotFound), "wrapped"),
+		http.StatusBadRequest,
+	)
+	assert.Equal(t, http.StatusBadRequest, StatusWithPolicy(err, Outermost))
+	assert.Equal(t, http.StatusNotFound, StatusWithPolicy(err, Innermost))
+	assert.Equal(t, http.StatusNotFound, StatusWithPolicy(err, Highest))
+
+	err = errors.Combine(
+		WithHTTPStatus(io.EOF, http.StatusNotFound),
+		WithHTTPStatus(io.EOF, http.StatusServiceUnavailable),
+		errConflict,
+	)
+	assert.Equal(t, http.StatusNotFound, StatusWithPolicy(err, Outermost))
+	assert.Equal(t, http.StatusNotFound, StatusWithPolicy(err, Innermost))
+	assert.Equal(t, http.StatusServiceUnavailable, StatusWithPolicy(err, Highest))
+
+	defer SetPolicy(SetPolicy(Highest))
+	assert.Equal(t, Highest, GetPolicy())
+	assert.Equal(t, http.StatusServiceUnavailable, Status(err))
+}
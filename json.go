@@ -0,0 +1,236 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSONSchema is the JSON Schema of the documents produced
+// by MarshalChain and the MarshalJSON methods of the errors
+// returned by this package.
+const JSONSchema = `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"$id": "https://github.com/domonda/errors/error.schema.json",
+	"title": "Error",
+	"$ref": "#/definitions/error",
+	"definitions": {
+		"error": {
+			"type": "object",
+			"required": ["type", "error"],
+			"properties": {
+				"type": {"type": "string"},
+				"error": {"type": "string"},
+				"message": {"type": "string"},
+				"fields": {"type": "object"},
+				"code": {},
+				"codeType": {"type": "string"},
+				"public": {"type": "string"},
+				"stack": {
+					"type": "array",
+					"items": {"$ref": "#/definitions/frame"}
+				},
+				"cause": {"$ref": "#/definitions/error"},
+				"errors": {
+					"type": "array",
+					"items": {"$ref": "#/definitions/error"}
+				}
+			}
+		},
+		"frame": {
+			"type": "object",
+			"required": ["function", "file", "line"],
+			"properties": {
+				"function": {"type": "string"},
+				"file": {"type": "string"},
+				"line": {"type": "integer"},
+				"package": {"type": "string"}
+			}
+		}
+	}
+}`
+
+// errorJSON is the JSON document of a single error in a chain
+// as described by JSONSchema.
+type errorJSON struct {
+	Type     string                 `json:"type"`
+	Error    string                 `json:"error"`
+	Message  string                 `json:"message,omitempty"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+	Code     interface{}            `json:"code,omitempty"`
+	CodeType string                 `json:"codeType,omitempty"`
+	Public   string                 `json:"public,omitempty"`
+	Stack    ResolvedStackTrace     `json:"stack,omitempty"`
+	Cause    *errorJSON             `json:"cause,omitempty"`
+	Errors   []*errorJSON           `json:"errors,omitempty"`
+}
+
+// MarshalChain returns the JSON document described by JSONSchema
+// for err and its complete chain of causes and combined errors.
+// Errors not created by this package are supported via their
+// Unwrap() error, Unwrap() []error, Cause() error, or Errors() []error methods.
+// Field values that can't be marshaled as JSON
+// are formatted as strings with fmt.Sprint.
+// If err is nil, the JSON null literal is returned.
+func MarshalChain(err error) ([]byte, error) {
+	if err == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(newErrorJSON(err))
+}
+
+// jsonValue returns the JSON of value or value formatted
+// with fmt.Sprint if it can't be marshaled, like functions,
+// channels, or cyclic values, so that one field value
+// doesn't make the whole chain fail.
+func jsonValue(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return json.RawMessage(data)
+}
+
+func newErrorJSON(err error) *errorJSON {
+	type causer interface {
+		Cause() error
+	}
+	type wrapper interface {
+		Unwrap() error
+	}
+	type multiWrapper interface {
+		Unwrap() []error
+	}
+	type remote interface {
+		remote() *remoteError
+	}
+
+	if r, ok := err.(remote); ok {
+		return newRemoteErrorJSON(r.remote())
+	}
+
+	j := &errorJSON{
+		Type:  fmt.Sprintf("%T", err),
+		Error: err.Error(),
+	}
+	switch e := err.(type) {
+	case *fundamental:
+		j.Message = e.msg
+		j.Stack = e.stack.ResolvedStackTrace()
+	case *withStack:
+		j.Stack = e.callStack.ResolvedStackTrace()
+		j.Cause = newErrorJSON(e.error)
+	case *withMessage:
+		j.Message = e.msg
+		j.Cause = newErrorJSON(e.cause)
+	case *withFields:
+		j.Fields = make(map[string]interface{}, len(e.fields))
+		for key, value := range e.fields {
+			j.Fields[key] = jsonValue(value)
+		}
+		j.Cause = newErrorJSON(e.cause)
+	case *withCode:
+		if e.code != nil {
+			j.Code = jsonValue(e.code)
+			j.CodeType = e.codeType
+			if j.CodeType == "" {
+				j.CodeType = fmt.Sprintf("%T", e.code)
+			}
+		}
+		j.Cause = newErrorJSON(e.cause)
+	case *withPublicMessage:
+		j.Public = e.public
+		j.Cause = newErrorJSON(e.cause)
+	case *combination:
+		j.Stack = e.stack.ResolvedStackTrace()
+		j.Errors = newErrorsJSON(e.errs)
+	case multiError:
+		j.Errors = newErrorsJSON(e.Errors())
+	case multiWrapper:
+		j.Errors = newErrorsJSON(e.Unwrap())
+	case causer:
+		if cause := e.Cause(); cause != nil {
+			j.Cause = newErrorJSON(cause)
+		}
+	case wrapper:
+		if cause := e.Unwrap(); cause != nil {
+			j.Cause = newErrorJSON(cause)
+		}
+	}
+	return j
+}
+
+func newRemoteErrorJSON(r *remoteError) *errorJSON {
+	j := &errorJSON{
+		Type:    r.typ,
+		Error:   r.err,
+		Message: r.msg,
+		Fields:  r.fields,
+		Stack:   r.stack,
+		Errors:  newErrorsJSON(r.errs),
+	}
+	if r.cause != nil {
+		j.Cause = newErrorJSON(r.cause)
+	}
+	return j
+}
+
+func newErrorsJSON(errs []error) []*errorJSON {
+	if len(errs) == 0 {
+		return nil
+	}
+	js := make([]*errorJSON, 0, len(errs))
+	for _, err := range errs {
+		if err != nil {
+			js = append(js, newErrorJSON(err))
+		}
+	}
+	return js
+}
+
+// MarshalJSON implements json.Marshaler
+// using the document described by JSONSchema.
+func (f *fundamental) MarshalJSON() ([]byte, error) {
+	return MarshalChain(f)
+}
+
+// MarshalJSON implements json.Marshaler
+// using the document described by JSONSchema.
+func (w *withStack) MarshalJSON() ([]byte, error) {
+	return MarshalChain(w)
+}
+
+// MarshalJSON implements json.Marshaler
+// using the document described by JSONSchema.
+func (w *withMessage) MarshalJSON() ([]byte, error) {
+	return MarshalChain(w)
+}
+
+// MarshalJSON implements json.Marshaler
+// using the document described by JSONSchema.
+func (w *withFields) MarshalJSON() ([]byte, error) {
+	return MarshalChain(w)
+}
+
+// MarshalJSON implements json.Marshaler
+// using the document described by JSONSchema.
+func (w *withCode) MarshalJSON() ([]byte, error) {
+	return MarshalChain(w)
+}
+
+// MarshalJSON implements json.Marshaler
+// using the document described by JSONSchema.
+func (w *withPublicMessage) MarshalJSON() ([]byte, error) {
+	return MarshalChain(w)
+}
+
+// MarshalJSON implements json.Marshaler
+// using the document described by JSONSchema.
+func (c *combination) MarshalJSON() ([]byte, error) {
+	return MarshalChain(c)
+}
+
+// MarshalJSON implements json.Marshaler
+// using the document described by JSONSchema.
+func (r *remoteError) MarshalJSON() ([]byte, error) {
+	return MarshalChain(r)
+}
@@ -0,0 +1,74 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MarshalChain(t *testing.T) {
+	data, err := MarshalChain(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "null", string(data))
+
+	data, err = MarshalChain(io.EOF)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"*errors.errorString","error":"EOF"}`, string(data))
+
+	data, err = MarshalChain(fmt.Errorf("stdlib: %w", WithField(io.EOF, "key", "value")))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"*fmt.wrapError","error":"stdlib: EOF","cause":`+
+		`{"type":"*errors.withFields","error":"EOF","fields":{"key":"value"},"cause":`+
+		`{"type":"*errors.errorString","error":"EOF"}}}`, string(data))
+}
+
+func Test_MarshalJSON(t *testing.T) {
+	var doc errorJSON
+
+	data, err := json.Marshal(Wrap(New("e0"), "wrapped"))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "*errors.withStack", doc.Type)
+	assert.Equal(t, "wrapped: e0", doc.Error)
+	assert.Equal(t, "github.com/domonda/errors.Test_MarshalJSON", doc.Stack[0].Function)
+	assert.Equal(t, 31, doc.Stack[0].Line)
+	assert.Equal(t, "*errors.withMessage", doc.Cause.Type)
+	assert.Equal(t, "wrapped", doc.Cause.Message)
+	assert.Equal(t, "*errors.fundamental", doc.Cause.Cause.Type)
+	assert.Equal(t, "e0", doc.Cause.Cause.Message)
+	assert.Equal(t, 31, doc.Cause.Cause.Stack[0].Line)
+	assert.Nil(t, doc.Cause.Cause.Cause)
+
+	doc = errorJSON{}
+	data, err = json.Marshal(Combine(io.EOF, New("e1")))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "*errors.combination", doc.Type)
+	assert.Equal(t, "EOF\ne1", doc.Error)
+	assert.Len(t, doc.Errors, 2)
+	assert.Equal(t, "*errors.errorString", doc.Errors[0].Type)
+	assert.Equal(t, "*errors.fundamental", doc.Errors[1].Type)
+}
+
+func Test_MarshalChainUnsupportedFields(t *testing.T) {
+	type node struct{ Next *node }
+	cyclic := &node{}
+	cyclic.Next = cyclic
+
+	err := WithFields(io.EOF, map[string]interface{}{"id": 1, "callback": func() {}, "node": cyclic})
+	data, e := MarshalChain(err)
+	assert.NoError(t, e)
+	var doc errorJSON
+	assert.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, float64(1), doc.Fields["id"])
+	_, ok := doc.Fields["callback"].(string)
+	assert.True(t, ok, "function formatted as string")
+	_, ok = doc.Fields["node"].(string)
+	assert.True(t, ok, "cyclic value formatted as string")
+
+	_, e = json.Marshal(struct{ Err error }{err})
+	assert.NoError(t, e)
+}
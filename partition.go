@@ -0,0 +1,132 @@
+package errors
+
+import "errors"
+
+// RemoveIs returns err without the combined errors
+// for which errors.Is returns true for target.
+// See RemoveFunc for details.
+func RemoveIs(err, target error) error {
+	return filterCombined(err, func(e error) bool { return !errors.Is(e, target) })
+}
+
+// RemoveFunc returns err without the combined errors
+// for which remove returns true.
+// The errors of err are determined with Uncombine,
+// so an error that is not a combination is
+// treated like a combination of itself.
+// If no error remains, then nil is returned,
+// if one error remains, then that error is returned,
+// and else a new combination of the remaining errors
+// with the CombineFormat of err.
+// If no error is removed, then err is returned unchanged.
+func RemoveFunc(err error, remove func(error) bool) error {
+	return filterCombined(err, func(e error) bool { return !remove(e) })
+}
+
+// Filter returns err with only the combined errors
+// for which keep returns true.
+// See RemoveFunc for details.
+func Filter(err error, keep func(error) bool) error {
+	return filterCombined(err, keep)
+}
+
+// Partition splits the combined errors of err into
+// the ones for which match returns true and the others.
+// match is called once for every combined error.
+// See RemoveFunc for details about the returned errors.
+func Partition(err error, match func(error) bool) (matching, others error) {
+	if err == nil {
+		return nil, nil
+	}
+	m := combinedMembers(err)
+	matches := m.match(match)
+	matching = m.filter(err, matches, true, 1)
+	others = m.filter(err, matches, false, 1)
+	return matching, others
+}
+
+// filterCombined returns err with only the combined errors
+// for which keep returns true.
+// A new combination gets the stack of the caller
+// of the function calling filterCombined.
+func filterCombined(err error, keep func(error) bool) error {
+	if err == nil {
+		return nil
+	}
+	m := combinedMembers(err)
+	return m.filter(err, m.match(keep), true, 2)
+}
+
+// members are the combined errors of an error
+// with their numbers of occurrences and the format
+// of the combination to create filtered combinations.
+type members struct {
+	errs      []error
+	counts    []int
+	format    *CombineFormat
+	dedup     DedupMode
+	originals bool
+}
+
+func combinedMembers(err error) members {
+	c, ok := err.(*combination)
+	if !ok {
+		return members{errs: Uncombine(err)}
+	}
+	errs, counts := c.errorCounts()
+	return members{
+		errs:      errs,
+		counts:    counts,
+		format:    c.format,
+		dedup:     c.dedup,
+		originals: c.originals,
+	}
+}
+
+// match returns the results of match for all errors.
+func (m *members) match(match func(error) bool) []bool {
+	matches := make([]bool, len(m.errs))
+	for i, e := range m.errs {
+		matches[i] = match(e)
+	}
+	return matches
+}
+
+// filter returns err with only the errors
+// for which matches has the value want.
+// A new combination gets the stack of the caller
+// of the function calling filter minus skip frames.
+func (m *members) filter(err error, matches []bool, want bool, skip int) error {
+	var (
+		kept       []error
+		keptCounts []int
+	)
+	for i, e := range m.errs {
+		if matches[i] != want {
+			continue
+		}
+		kept = append(kept, e)
+		if m.counts != nil {
+			keptCounts = append(keptCounts, m.counts[i])
+		}
+	}
+
+	switch {
+	case len(kept) == len(m.errs):
+		return err
+	case len(kept) == 0:
+		return nil
+	case len(kept) == 1 && (keptCounts == nil || keptCounts[0] == 1):
+		return kept[0]
+	}
+	var c *combination
+	if m.originals {
+		// Count the duplicates of the kept originals again
+		c = newCombination(kept, nil, m.dedup, true)
+	} else {
+		c = newCombination(kept, keptCounts, DedupNone, false)
+	}
+	c.format = m.format
+	c.stack = callers(skip, nil)
+	return c
+}
@@ -0,0 +1,75 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RemoveIs(t *testing.T) {
+	e0 := Wrap(io.EOF, "e0")
+	e1 := New("e1")
+	e2 := fmt.Errorf("e2: %w", io.EOF)
+
+	err := RemoveIs(Combine(e0, e1, e2), io.EOF)
+	assert.Equal(t, e1, err)
+
+	err = RemoveIs(Combine(e0, e1, e2, New("e3")), io.EOF)
+	assert.EqualError(t, err, "e1\ne3")
+	assert.Contains(t, fmt.Sprintf("%+v", err), "partition_test.go")
+
+	combined := Combine(e0, e1)
+	assert.Equal(t, combined, RemoveIs(combined, io.ErrUnexpectedEOF))
+	assert.NoError(t, RemoveIs(Combine(e0, e2), io.EOF))
+	assert.NoError(t, RemoveIs(e0, io.EOF))
+	assert.NoError(t, RemoveIs(nil, io.EOF))
+}
+
+func Test_FilterPartition(t *testing.T) {
+	isTemporary := func(err error) bool { return strings.HasPrefix(err.Error(), "temporary") }
+	err := CombineWith(
+		CombineSingleLine,
+		Const("temporary 1"),
+		Const("permanent 1"),
+		Const("temporary 2"),
+		Const("permanent 2"),
+	)
+
+	assert.EqualError(t, Filter(err, isTemporary), "temporary 1; temporary 2")
+	assert.EqualError(t, RemoveFunc(err, isTemporary), "permanent 1; permanent 2")
+
+	temporary, permanent := Partition(err, isTemporary)
+	assert.EqualError(t, temporary, "temporary 1; temporary 2")
+	assert.EqualError(t, permanent, "permanent 1; permanent 2")
+
+	temporary, permanent = Partition(Const("permanent"), isTemporary)
+	assert.NoError(t, temporary)
+	assert.Equal(t, Const("permanent"), permanent)
+
+	// Counted duplicates are kept
+	err = CombineWith(CombineFormat{Dedup: DedupMessage}, Const("a"), Const("a"), Const("b"))
+	assert.EqualError(t, RemoveIs(err, Const("b")), "a (x2)")
+
+	// Kept duplicates are counted again
+	err = CombineWith(CombineFormat{Dedup: DedupMessage, KeepDuplicates: true}, Const("a"), Const("a"), Const("b"), Const("c"))
+	err = RemoveIs(err, Const("b"))
+	assert.EqualError(t, err, "a (x2)\nc")
+	assert.Len(t, Uncombine(err), 3)
+}
+
+func Test_PartitionMatchOnce(t *testing.T) {
+	calls := 0
+	isEven := func(err error) bool {
+		calls++
+		return calls%2 == 0
+	}
+	matching, others := Partition(Combine(Const("e1"), Const("e2"), Const("e3"), Const("e4")), isEven)
+	assert.Equal(t, 4, calls)
+	assert.EqualError(t, matching, "e2\ne4")
+	assert.EqualError(t, others, "e1\ne3")
+	assert.Contains(t, fmt.Sprintf("%+v", matching), "partition_test.go")
+	assert.Contains(t, fmt.Sprintf("%+v", others), "partition_test.go")
+}
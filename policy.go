@@ -0,0 +1,358 @@
+package errors
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultStackDepth is the maximum number of captured stack frames
+// used when StackPolicy.MaxDepth is not greater than zero.
+const DefaultStackDepth = 32
+
+// StackPolicyEnv is the name of the environment variable
+// that is parsed with ParseStackPolicy to initialize
+// the package level stack policy.
+// An invalid value is reported on stderr and by StackPolicyEnvError,
+// and the default stack policy is used instead.
+const StackPolicyEnv = "ERRORS_STACK_POLICY"
+
+// StackMode selects when a stack trace is captured.
+type StackMode int
+
+const (
+	// StackAlways captures a stack trace for every error.
+	StackAlways StackMode = iota
+
+	// StackNever never captures stack traces,
+	// for example for hot paths in production
+	// where stack traces are never printed.
+	StackNever
+
+	// StackFirstWrap captures a stack trace only for errors
+	// that don't have a stack trace in their chain of causes yet.
+	StackFirstWrap
+
+	// StackFirstWrapCaller captures a complete stack trace for errors
+	// that don't have a stack trace in their chain of causes yet,
+	// and only the frame of the calling function otherwise.
+	// This is the mode of the default stack policy.
+	StackFirstWrapCaller
+)
+
+var stackModeNames = map[StackMode]string{
+	StackAlways:          "always",
+	StackNever:           "never",
+	StackFirstWrap:       "firstwrap",
+	StackFirstWrapCaller: "firstwrapcaller",
+}
+
+// defaultStackPolicy avoids redundant stack traces
+// when wrapping errors that already have one.
+var defaultStackPolicy = StackPolicy{Mode: StackFirstWrapCaller}
+
+// String returns the name of the mode as used by ParseStackPolicy.
+func (m StackMode) String() string {
+	if name, ok := stackModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("StackMode(%d)", int(m))
+}
+
+// StackPolicy configures how stack traces are captured.
+//
+// The package level policy used by New, Errorf, WithStack,
+// Wrap, Wrapf, Combine, and their variants can be changed with
+// SetStackPolicy or the environment variable ERRORS_STACK_POLICY.
+// The methods of StackPolicy create errors with a
+// specific policy for a single call.
+type StackPolicy struct {
+	// Mode selects when a stack trace is captured.
+	Mode StackMode
+
+	// MaxDepth is the maximum number of captured frames.
+	// DefaultStackDepth is used if MaxDepth is not greater than zero.
+	MaxDepth int
+
+	// SampleEvery captures a stack trace only for
+	// every n-th error that would otherwise get one.
+	// Every policy counts its errors separately.
+	// Stack traces are not sampled if SampleEvery is less than 2.
+	SampleEvery int
+}
+
+// ParseStackPolicy parses a comma separated list of
+// key=value pairs with the keys "mode", "depth", and "sample"
+// corresponding to the StackPolicy fields Mode, MaxDepth, and SampleEvery.
+// Valid modes are "always", "never", "firstwrap", and "firstwrapcaller",
+// depth and sample must not be negative.
+// Not specified fields have the values of the default policy
+// which uses the mode StackFirstWrapCaller.
+// Example:
+//     mode=firstwrap,depth=64,sample=10
+func ParseStackPolicy(str string) (policy StackPolicy, err error) {
+	policy = defaultStackPolicy
+	for _, pair := range strings.Split(str, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		eq := strings.IndexByte(pair, '=')
+		if eq == -1 {
+			return StackPolicy{}, Errorf("invalid stack policy %q: missing '=' in %q", str, pair)
+		}
+		key, value := strings.TrimSpace(pair[:eq]), strings.TrimSpace(pair[eq+1:])
+		switch key {
+		case "mode":
+			found := false
+			for mode, name := range stackModeNames {
+				if value == name {
+					policy.Mode = mode
+					found = true
+				}
+			}
+			if !found {
+				return StackPolicy{}, Errorf("invalid stack policy %q: unknown mode %q", str, value)
+			}
+		case "depth":
+			policy.MaxDepth, err = strconv.Atoi(value)
+			if err != nil {
+				return StackPolicy{}, Wrapf(err, "invalid stack policy %q", str)
+			}
+			if policy.MaxDepth < 0 {
+				return StackPolicy{}, Errorf("invalid stack policy %q: negative depth %d", str, policy.MaxDepth)
+			}
+		case "sample":
+			policy.SampleEvery, err = strconv.Atoi(value)
+			if err != nil {
+				return StackPolicy{}, Wrapf(err, "invalid stack policy %q", str)
+			}
+			if policy.SampleEvery < 0 {
+				return StackPolicy{}, Errorf("invalid stack policy %q: negative sample %d", str, policy.SampleEvery)
+			}
+		default:
+			return StackPolicy{}, Errorf("invalid stack policy %q: unknown key %q", str, key)
+		}
+	}
+	return policy, nil
+}
+
+var (
+	stackPolicy       atomic.Value // StackPolicy
+	stackPolicyEnvErr error
+
+	// stackSamples holds the *uint64 sampling counter per StackPolicy
+	stackSamples sync.Map
+)
+
+func init() {
+	policy, err := parseStackPolicyEnv(os.Getenv(StackPolicyEnv))
+	if err != nil {
+		// Don't fail the program because of an invalid
+		// environment variable, but don't ignore it silently
+		fmt.Fprintf(os.Stderr, "%s, using the default stack policy\n", err)
+		stackPolicyEnvErr = err
+	}
+	stackPolicy.Store(policy)
+}
+
+// parseStackPolicyEnv parses the value of the environment variable
+// ERRORS_STACK_POLICY and returns the default policy
+// together with the parsing error if value is invalid.
+func parseStackPolicyEnv(value string) (StackPolicy, error) {
+	policy, err := ParseStackPolicy(value)
+	if err != nil {
+		return defaultStackPolicy, Wrapf(err, "environment variable %s", StackPolicyEnv)
+	}
+	return policy, nil
+}
+
+// StackPolicyEnvError returns the error from parsing the
+// environment variable ERRORS_STACK_POLICY at program start
+// or nil if it was valid or not set.
+// The default stack policy is used if the variable was invalid.
+func StackPolicyEnvError() error {
+	return stackPolicyEnvErr
+}
+
+// SetStackPolicy sets the package level stack policy
+// and returns the previous one.
+// It is safe to call SetStackPolicy concurrently
+// with the creation of errors.
+func SetStackPolicy(policy StackPolicy) (previous StackPolicy) {
+	previous = GetStackPolicy()
+	stackPolicy.Store(policy)
+	return previous
+}
+
+// GetStackPolicy returns the package level stack policy.
+func GetStackPolicy() StackPolicy {
+	policy, ok := stackPolicy.Load().(StackPolicy)
+	if !ok {
+		// Load returns nil before init stored the initial policy
+		return defaultStackPolicy
+	}
+	return policy
+}
+
+// New returns an error with the supplied message
+// and a stack trace captured according to the policy.
+func (p StackPolicy) New(message string) error {
+	return &fundamental{
+		msg:   message,
+		stack: p.callers(0, nil),
+	}
+}
+
+// Errorf formats according to a format specifier and returns the string
+// as a value that satisfies error with a stack trace captured according to the policy.
+func (p StackPolicy) Errorf(format string, args ...interface{}) error {
+	return &fundamental{
+		msg:   fmt.Sprintf(format, args...),
+		stack: p.callers(0, nil),
+	}
+}
+
+// WithStack annotates err with a stack trace captured according to the policy.
+// If err is nil, WithStack returns nil.
+func (p StackPolicy) WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &withStack{
+		err,
+		p.callers(0, err),
+	}
+}
+
+// Wrap returns an error annotating err with a stack trace
+// captured according to the policy, and the supplied message.
+// If err is nil, Wrap returns nil.
+func (p StackPolicy) Wrap(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	err = &withMessage{
+		cause: err,
+		msg:   message,
+	}
+	return &withStack{
+		err,
+		p.callers(0, err),
+	}
+}
+
+// Wrapf returns an error annotating err with a stack trace
+// captured according to the policy, and the format specifier.
+// If err is nil, Wrapf returns nil.
+func (p StackPolicy) Wrapf(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	err = &withMessage{
+		cause: err,
+		msg:   fmt.Sprintf(format, args...),
+	}
+	return &withStack{
+		err,
+		p.callers(0, err),
+	}
+}
+
+// emptyStack is shared by all errors without a captured stack trace.
+var emptyStack stack
+
+// callers captures the stack of the caller of the function calling callers
+// minus skipExtra frames according to the policy.
+// The chain of cause is checked for existing stack traces
+// in the StackFirstWrap and StackFirstWrapCaller modes, cause may be nil.
+func (p StackPolicy) callers(skipExtra int, cause error) *stack {
+	depth := p.MaxDepth
+	if depth <= 0 {
+		depth = DefaultStackDepth
+	}
+	switch p.Mode {
+	case StackNever:
+		return &emptyStack
+	case StackFirstWrap:
+		if causeHasStack(cause) {
+			return &emptyStack
+		}
+	case StackFirstWrapCaller:
+		if causeHasStack(cause) {
+			depth = 1
+		}
+	}
+	if p.SampleEvery > 1 && atomic.AddUint64(p.samples(), 1)%uint64(p.SampleEvery) != 0 {
+		return &emptyStack
+	}
+
+	var (
+		buf [DefaultStackDepth]uintptr
+		pcs []uintptr
+	)
+	if depth > len(buf) {
+		pcs = make([]uintptr, depth)
+	} else {
+		pcs = buf[:depth]
+	}
+	n := runtime.Callers(3+skipExtra, pcs)
+	st := &stack{pcs: make([]uintptr, n)}
+	copy(st.pcs, pcs)
+	return st
+}
+
+// samples returns the sampling counter of the policy.
+// Equal policies share the same counter.
+func (p StackPolicy) samples() *uint64 {
+	if counter, ok := stackSamples.Load(p); ok {
+		return counter.(*uint64)
+	}
+	counter, _ := stackSamples.LoadOrStore(p, new(uint64))
+	return counter.(*uint64)
+}
+
+// causeHasStack returns if err or any error
+// in its chain of causes has a stack trace,
+// including errors of other packages with a
+// StackTrace method returning a non empty StackTrace.
+func causeHasStack(err error) bool {
+	type causer interface {
+		Cause() error
+	}
+	type wrapper interface {
+		Unwrap() error
+	}
+	type stacked interface {
+		hasStack() bool
+	}
+	type stackTracer interface {
+		StackTrace() StackTrace
+	}
+
+	for err != nil {
+		switch s := err.(type) {
+		case stacked:
+			if s.hasStack() {
+				return true
+			}
+		case stackTracer:
+			if len(s.StackTrace()) > 0 {
+				return true
+			}
+		}
+		switch e := err.(type) {
+		case causer:
+			err = e.Cause()
+		case wrapper:
+			err = e.Unwrap()
+		default:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stackLen(err error) int {
+	return len(err.(interface{ StackTrace() StackTrace }).StackTrace())
+}
+
+func Test_ParseStackPolicy(t *testing.T) {
+	tests := map[string]StackPolicy{
+		"":                                  {Mode: StackFirstWrapCaller},
+		"mode=always":                       {Mode: StackAlways},
+		" mode = never ":                    {Mode: StackNever},
+		"mode=firstwrap,depth=64,sample=10": {Mode: StackFirstWrap, MaxDepth: 64, SampleEvery: 10},
+		"mode=firstwrapcaller":              {Mode: StackFirstWrapCaller},
+		"depth=8,":                          {Mode: StackFirstWrapCaller, MaxDepth: 8},
+	}
+	for str, want := range tests {
+		policy, err := ParseStackPolicy(str)
+		assert.NoError(t, err, str)
+		assert.Equal(t, want, policy, str)
+	}
+
+	for _, str := range []string{"mode", "mode=sometimes", "depth=deep", "sample=x", "color=red", "depth=-5", "sample=-3", "depth=-5,sample=-3"} {
+		_, err := ParseStackPolicy(str)
+		assert.Error(t, err, str)
+	}
+}
+
+func Test_SetStackPolicy(t *testing.T) {
+	defer SetStackPolicy(SetStackPolicy(StackPolicy{Mode: StackNever}))
+
+	assert.Equal(t, StackPolicy{Mode: StackNever}, GetStackPolicy())
+	assert.Equal(t, 0, stackLen(New("e")))
+	assert.Equal(t, 0, stackLen(Wrap(io.EOF, "e")))
+	assert.Equal(t, "e", fmt.Sprintf("%+v", New("e")))
+
+	SetStackPolicy(StackPolicy{Mode: StackFirstWrap})
+	err := New("e")
+	assert.NotEqual(t, 0, stackLen(err))
+	assert.Equal(t, 0, stackLen(Wrap(err, "wrapped")))
+	assert.Equal(t, 0, stackLen(WithStack(WithMessage(err, "message"))))
+	assert.NotEqual(t, 0, stackLen(Wrap(io.EOF, "wrapped")))
+	assert.NotEqual(t, 0, stackLen(WithStack(io.EOF)))
+
+	SetStackPolicy(StackPolicy{Mode: StackFirstWrapCaller})
+	err = New("e")
+	assert.True(t, stackLen(err) > 1)
+	assert.Equal(t, 1, stackLen(Wrap(err, "wrapped")))
+	assert.Equal(t, 1, stackLen(WithStack(WithMessage(err, "message"))))
+	assert.True(t, stackLen(Wrap(io.EOF, "wrapped")) > 1)
+
+	SetStackPolicy(StackPolicy{MaxDepth: 1})
+	assert.Equal(t, 1, stackLen(New("e")))
+
+	SetStackPolicy(StackPolicy{MaxDepth: 100})
+	assert.Equal(t, stackLen(StackPolicy{}.New("e")), stackLen(New("e")))
+
+	SetStackPolicy(StackPolicy{SampleEvery: 3})
+	captured := 0
+	for i := 0; i < 30; i++ {
+		if stackLen(New("e")) > 0 {
+			captured++
+		}
+	}
+	assert.Equal(t, 10, captured)
+}
+
+func Test_StackPolicyMethods(t *testing.T) {
+	never := StackPolicy{Mode: StackNever}
+	assert.EqualError(t, never.New("e"), "e")
+	assert.Equal(t, 0, stackLen(never.New("e")))
+	assert.EqualError(t, never.Errorf("e%d", 1), "e1")
+	assert.Equal(t, 0, stackLen(never.Errorf("e%d", 1)))
+	assert.Equal(t, 0, stackLen(never.WithStack(io.EOF)))
+	assert.EqualError(t, never.Wrap(io.EOF, "wrapped"), "wrapped: EOF")
+	assert.EqualError(t, never.Wrapf(io.EOF, "wrapped%d", 1), "wrapped1: EOF")
+	assert.Equal(t, io.EOF, Cause(never.Wrapf(io.EOF, "wrapped%d", 1)))
+	assert.NoError(t, never.WithStack(nil))
+	assert.NoError(t, never.Wrap(nil, "wrapped"))
+	assert.NoError(t, never.Wrapf(nil, "wrapped"))
+
+	testFormatRegexp(t, 0, StackPolicy{}.Wrap(io.EOF, "wrapped"), "%+v", "EOF\n"+
+		"wrapped\n"+
+		"github.com/domonda/errors.Test_StackPolicyMethods\n"+
+		"\t.+/github.com/domonda/errors/policy_test.go:89")
+}
+
+func Test_StackPolicyEnv(t *testing.T) {
+	policy, err := parseStackPolicyEnv("mode=never")
+	assert.NoError(t, err)
+	assert.Equal(t, StackPolicy{Mode: StackNever}, policy)
+
+	policy, err = parseStackPolicyEnv("mode=sometimes")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), StackPolicyEnv)
+	assert.Contains(t, err.Error(), `unknown mode "sometimes"`)
+	assert.Equal(t, defaultStackPolicy, policy)
+
+	// The variable is not set for the tests
+	assert.NoError(t, StackPolicyEnvError())
+}
+
+func Test_StackPolicySamplingPerPolicy(t *testing.T) {
+	every2 := StackPolicy{Mode: StackAlways, SampleEvery: 2}
+	every3 := StackPolicy{Mode: StackAlways, SampleEvery: 3, MaxDepth: 7}
+	captured2, captured3 := 0, 0
+	for i := 0; i < 12; i++ {
+		// Interleaved calls must not influence the sampling of each other
+		if stackLen(every2.New("e")) > 0 {
+			captured2++
+		}
+		if stackLen(every3.New("e")) > 0 {
+			captured3++
+		}
+	}
+	assert.Equal(t, 6, captured2)
+	assert.Equal(t, 4, captured3)
+}
+
+// foreignStackError has a stack trace
+// like the errors of github.com/pkg/errors
+type foreignStackError struct {
+	stack StackTrace
+}
+
+func (e foreignStackError) Error() string          { return "foreign" }
+func (e foreignStackError) StackTrace() StackTrace { return e.stack }
+
+func Test_StackPolicyForeignStack(t *testing.T) {
+	type stackTracer interface {
+		StackTrace() StackTrace
+	}
+
+	foreign := foreignStackError{New("e").(stackTracer).StackTrace()}
+	assert.NotEqual(t, 0, len(foreign.stack))
+
+	assert.Equal(t, 0, stackLen(StackPolicy{Mode: StackFirstWrap}.Wrap(foreign, "wrapped")))
+	assert.Equal(t, 1, stackLen(StackPolicy{Mode: StackFirstWrapCaller}.Wrap(foreign, "wrapped")))
+	assert.NotEqual(t, 0, stackLen(StackPolicy{Mode: StackFirstWrap}.Wrap(foreignStackError{}, "wrapped")))
+}
@@ -0,0 +1,151 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// DefaultPublicMessage is the initial generic message
+// returned by PublicMessage for errors without a public message.
+const DefaultPublicMessage = "An internal error occurred"
+
+// WithPublicMessage annotates err with a message that is
+// safe to be shown to end users, in contrast to the messages
+// of wrapped errors that can contain internal details
+// like SQL statements, file paths, or call signatures.
+// The Error method of the returned error returns err.Error() unchanged,
+// the public message can be retrieved with the PublicMessage function.
+// If err is nil, WithPublicMessage returns nil.
+func WithPublicMessage(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &withPublicMessage{
+		cause:  err,
+		public: message,
+	}
+}
+
+// Publicf annotates err with a public message formatted
+// according to a format specifier, see WithPublicMessage.
+// If err is nil, Publicf returns nil.
+func Publicf(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return &withPublicMessage{
+		cause:  err,
+		public: fmt.Sprintf(format, args...),
+	}
+}
+
+type withPublicMessage struct {
+	cause  error
+	public string
+}
+
+func (w *withPublicMessage) Error() string {
+	return w.cause.Error()
+}
+
+func (w *withPublicMessage) Cause() error {
+	return w.cause
+}
+
+func (w *withPublicMessage) Unwrap() error {
+	return w.cause
+}
+
+func (w *withPublicMessage) PublicMessage() string {
+	return w.public
+}
+
+func (w *withPublicMessage) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%+v\npublic=%q", w.Cause(), w.public)
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, w.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", w.Error())
+	}
+}
+
+var defaultPublicMessage atomic.Value // string
+
+// SetDefaultPublicMessage sets the generic message returned
+// by PublicMessage for errors without a public message
+// and returns the previous one.
+func SetDefaultPublicMessage(message string) (previous string) {
+	previous = GetDefaultPublicMessage()
+	defaultPublicMessage.Store(message)
+	return previous
+}
+
+// GetDefaultPublicMessage returns the generic message returned
+// by PublicMessage for errors without a public message.
+func GetDefaultPublicMessage() string {
+	message, ok := defaultPublicMessage.Load().(string)
+	if !ok {
+		return DefaultPublicMessage
+	}
+	return message
+}
+
+// PublicMessage returns the outermost public message of err
+// and the errors in its tree of causes and combined errors,
+// which is the message with the least wrapping depth
+// as traversed by Walk, or the generic default message
+// set with SetDefaultPublicMessage if there is none.
+// If err is nil, an empty string is returned.
+//
+// Public messages are attached with WithPublicMessage and Publicf
+// or by errors that implement the following interface:
+//
+//     type publicMessager interface {
+//             PublicMessage() string
+//     }
+func PublicMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	message, ok := findPublicMessage(err)
+	if !ok {
+		return GetDefaultPublicMessage()
+	}
+	return message
+}
+
+// HasPublicMessage returns if err or any error in its tree
+// of causes and combined errors has a public message,
+// so that PublicMessage does not return the generic default message.
+func HasPublicMessage(err error) bool {
+	_, ok := findPublicMessage(err)
+	return ok
+}
+
+// findPublicMessage returns the public message
+// with the least wrapping depth.
+func findPublicMessage(err error) (message string, ok bool) {
+	type publicMessager interface {
+		PublicMessage() string
+	}
+
+	messageDepth := -1
+	Walk(err, func(e error, depth int) bool {
+		if messageDepth != -1 && depth >= messageDepth {
+			return false
+		}
+		if p, ok := e.(publicMessager); ok && p.PublicMessage() != "" {
+			message, messageDepth = p.PublicMessage(), depth
+			return false
+		}
+		return true
+	})
+	return message, messageDepth != -1
+}
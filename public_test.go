@@ -0,0 +1,77 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PublicMessage(t *testing.T) {
+	assert.NoError(t, WithPublicMessage(nil, "public"))
+	assert.NoError(t, Publicf(nil, "public %d", 1))
+	assert.Equal(t, "", PublicMessage(nil))
+	assert.Equal(t, DefaultPublicMessage, PublicMessage(io.EOF))
+
+	// Error keeps the internal details
+	internal := Wrap(io.EOF, "CALL: loadDocument(\"/var/data/secret.json\")")
+	err := WithPublicMessage(internal, "The document could not be loaded")
+	assert.Equal(t, internal.Error(), err.Error())
+	assert.Equal(t, io.EOF, Cause(err))
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, "The document could not be loaded", PublicMessage(err))
+	assert.Equal(t, "The document could not be loaded", PublicMessage(Wrap(err, "SELECT * FROM document")))
+	assert.NotContains(t, PublicMessage(err), "secret")
+
+	// The outermost public message is returned
+	err = Publicf(Wrap(err, "wrapped"), "Document %d could not be loaded", 7)
+	assert.Equal(t, "Document 7 could not be loaded", PublicMessage(err))
+
+	err = Combine(Wrap(WithPublicMessage(io.EOF, "deeper"), "wrapped"), WithPublicMessage(io.EOF, "outer"))
+	assert.Equal(t, "outer", PublicMessage(err))
+}
+
+func Test_SetDefaultPublicMessage(t *testing.T) {
+	previous := SetDefaultPublicMessage("Something went wrong")
+	defer SetDefaultPublicMessage(previous)
+
+	assert.Equal(t, DefaultPublicMessage, previous)
+	assert.Equal(t, "Something went wrong", GetDefaultPublicMessage())
+	assert.Equal(t, "Something went wrong", PublicMessage(io.EOF))
+}
+
+func TestFormatWithPublicMessage(t *testing.T) {
+	err := WithPublicMessage(Const("internal"), "public")
+	assert.Equal(t, "internal", fmt.Sprintf("%s", err))
+	assert.Equal(t, `"internal"`, fmt.Sprintf("%q", err))
+	assert.Equal(t, "internal\npublic=\"public\"", fmt.Sprintf("%+v", err))
+}
+
+func Test_HasPublicMessage(t *testing.T) {
+	assert.False(t, HasPublicMessage(nil))
+	assert.False(t, HasPublicMessage(io.EOF))
+	assert.False(t, HasPublicMessage(WithPublicMessage(io.EOF, "")))
+	assert.True(t, HasPublicMessage(Wrap(WithPublicMessage(io.EOF, "p"), "wrapped")))
+}
+
+func Test_PublicMessageJSON(t *testing.T) {
+	err := WithPublicMessage(io.EOF, "p")
+	data, e := json.Marshal(err)
+	assert.NoError(t, e)
+	assert.JSONEq(t, `{"type":"*errors.withPublicMessage","error":"EOF","public":"p","cause":{"type":"*errors.errorString","error":"EOF"}}`, string(data))
+
+	data, e = MarshalChain(Wrap(Publicf(WithCode(io.EOF, 1), "public %d", 1), "wrapped"))
+	assert.NoError(t, e)
+	remote, e := FromJSON(data)
+	assert.NoError(t, e)
+	assert.EqualError(t, remote, "wrapped: EOF")
+	assert.Equal(t, "public 1", PublicMessage(remote))
+	assert.Equal(t, float64(1), Code(remote))
+	assert.True(t, errors.Is(remote, io.EOF))
+	remoteData, e := MarshalChain(remote)
+	assert.NoError(t, e)
+	assert.Equal(t, string(data), string(remoteData))
+}
@@ -0,0 +1,250 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var (
+	sentinels    = make(map[sentinelKey]error)
+	sentinelsMtx sync.RWMutex
+
+	constTypeName    = fmt.Sprintf("%T", Const(""))
+	withCodeTypeName = fmt.Sprintf("%T", (*withCode)(nil))
+	publicTypeName   = fmt.Sprintf("%T", (*withPublicMessage)(nil))
+)
+
+type sentinelKey struct {
+	typ string
+	msg string
+}
+
+// RegisterSentinels registers sentinel error values like io.EOF
+// so that FromJSON returns the registered value itself
+// instead of a reconstructed remote error for a serialized error
+// with the same type name and message and without a cause.
+// This makes comparisons with == and errors.As work for
+// sentinel errors after a round trip through JSON.
+// Const errors don't need to be registered because
+// FromJSON always reconstructs them as Const values.
+func RegisterSentinels(errs ...error) {
+	sentinelsMtx.Lock()
+	defer sentinelsMtx.Unlock()
+
+	for _, err := range errs {
+		if err != nil {
+			sentinels[sentinelKey{fmt.Sprintf("%T", err), err.Error()}] = err
+		}
+	}
+}
+
+// UnregisterSentinels removes sentinel error values
+// registered with RegisterSentinels.
+func UnregisterSentinels(errs ...error) {
+	sentinelsMtx.Lock()
+	defer sentinelsMtx.Unlock()
+
+	for _, err := range errs {
+		if err != nil {
+			delete(sentinels, sentinelKey{fmt.Sprintf("%T", err), err.Error()})
+		}
+	}
+}
+
+func registeredSentinel(typ, msg string) error {
+	sentinelsMtx.RLock()
+	defer sentinelsMtx.RUnlock()
+
+	return sentinels[sentinelKey{typ, msg}]
+}
+
+// FromJSON reconstructs an error from the JSON document described by JSONSchema
+// as returned by MarshalChain or the MarshalJSON methods of the errors of this package.
+//
+// The reconstructed remote errors return the original Error strings,
+// support Cause, Unwrap, Uncombine, and Fields, and format their stack traces
+// with %+v from the serialized frames.
+// A remote error without a cause is matched by errors.Is against
+// a target with the same type name and message, like a Const with
+// the same message.
+// Serialized Const errors are reconstructed as Const values and
+// errors registered with RegisterSentinels as the registered values.
+// Errors annotated with WithCode are reconstructed with their codes,
+// see RegisterCodes, and errors annotated with WithPublicMessage
+// with their public messages.
+//
+// Note that numeric field values are reconstructed as float64.
+// If data is the JSON null literal, then nil is returned as remote error.
+func FromJSON(data []byte) (remote error, err error) {
+	var doc *errorJSON
+	err = json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, nil
+	}
+	return fromErrorJSON(doc), nil
+}
+
+func fromErrorJSON(doc *errorJSON) error {
+	var members []error
+	for _, member := range doc.Errors {
+		if member != nil {
+			members = append(members, fromErrorJSON(member))
+		}
+	}
+
+	if doc.Type == withCodeTypeName && doc.Cause != nil && len(members) == 0 {
+		w := &withCode{cause: fromErrorJSON(doc.Cause)}
+		if code, ok := registeredCode(doc.CodeType, doc.Code); ok {
+			w.code = code
+		} else {
+			w.code, w.codeType = doc.Code, doc.CodeType
+		}
+		return w
+	}
+
+	if doc.Type == publicTypeName && doc.Cause != nil && len(members) == 0 {
+		return &withPublicMessage{
+			cause:  fromErrorJSON(doc.Cause),
+			public: doc.Public,
+		}
+	}
+
+	if doc.Cause == nil && len(members) == 0 {
+		if doc.Type == constTypeName {
+			return Const(doc.Error)
+		}
+		if sentinel := registeredSentinel(doc.Type, doc.Error); sentinel != nil {
+			return sentinel
+		}
+	}
+
+	r := &remoteError{
+		typ:    doc.Type,
+		err:    doc.Error,
+		msg:    doc.Message,
+		fields: doc.Fields,
+		stack:  doc.Stack,
+	}
+	switch {
+	case len(members) > 0:
+		r.errs = members
+		return remoteCombination{r}
+	case doc.Cause != nil:
+		r.cause = fromErrorJSON(doc.Cause)
+		return remoteWrapper{r}
+	}
+	return r
+}
+
+// remoteError is an error reconstructed by FromJSON
+// without a cause or combined errors.
+// The types remoteWrapper and remoteCombination
+// embed remoteError to add the methods for errors
+// with a cause or combined errors.
+type remoteError struct {
+	typ    string
+	err    string
+	msg    string
+	fields map[string]interface{}
+	stack  ResolvedStackTrace
+	cause  error
+	errs   []error
+}
+
+func (r *remoteError) remote() *remoteError { return r }
+
+func (r *remoteError) Error() string { return r.err }
+
+func (r *remoteError) ResolvedStackTrace() ResolvedStackTrace { return r.stack }
+
+func (r *remoteError) hasStack() bool { return len(r.stack) > 0 }
+
+func (r *remoteError) Is(target error) bool {
+	if r.cause != nil || len(r.errs) > 0 {
+		return false
+	}
+	if c, ok := target.(Const); ok {
+		return r.err == string(c)
+	}
+	return r.typ == fmt.Sprintf("%T", target) && r.err == target.Error()
+}
+
+func (r *remoteError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			switch {
+			case len(r.errs) > 0:
+				formatMembers(s, r.errs)
+			case r.cause != nil:
+				fmt.Fprintf(s, "%+v", r.cause)
+				if r.msg != "" {
+					io.WriteString(s, "\n"+r.msg)
+				}
+				if len(r.fields) > 0 {
+					io.WriteString(s, "\n"+formatFields(r.fields))
+				}
+			default:
+				io.WriteString(s, r.err)
+			}
+			r.stack.Format(s, verb)
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, r.err)
+	case 'q':
+		fmt.Fprintf(s, "%q", r.err)
+	}
+}
+
+// remoteWrapper is a remoteError with a cause.
+type remoteWrapper struct {
+	*remoteError
+}
+
+func (r remoteWrapper) Cause() error { return r.cause }
+
+func (r remoteWrapper) Unwrap() error { return r.cause }
+
+// remoteCombination is a remoteError with combined errors.
+type remoteCombination struct {
+	*remoteError
+}
+
+func (r remoteCombination) Cause() error {
+	if len(r.errs) == 0 {
+		// remoteError has no Cause method
+		// so it ends the chain of causes
+		return r.remoteError
+	}
+	return Cause(r.errs[0])
+}
+
+func (r remoteCombination) Errors() []error { return r.errs }
+
+func (r remoteCombination) Unwrap() []error { return r.errs }
+
+func (r remoteCombination) Is(target error) bool {
+	for _, err := range r.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r remoteCombination) As(target interface{}) bool {
+	for _, err := range r.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
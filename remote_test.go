@@ -0,0 +1,112 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FromJSON(t *testing.T) {
+	remote, err := FromJSON([]byte("null"))
+	assert.NoError(t, err)
+	assert.NoError(t, remote)
+
+	_, err = FromJSON([]byte("{"))
+	assert.Error(t, err)
+
+	const errConst = Const("const error")
+
+	original := Wrap(WithField(errConst, "documentID", "abc"), "wrapped")
+	data, err := json.Marshal(original)
+	assert.NoError(t, err)
+	remote, err = FromJSON(data)
+	assert.NoError(t, err)
+
+	assert.EqualError(t, remote, "wrapped: const error")
+	assert.Equal(t, errConst, Cause(remote))
+	assert.True(t, errors.Is(remote, errConst))
+	assert.Equal(t, map[string]interface{}{"documentID": "abc"}, Fields(remote))
+
+	stack := remote.(interface{ ResolvedStackTrace() ResolvedStackTrace }).ResolvedStackTrace()
+	assert.Equal(t, "github.com/domonda/errors.Test_FromJSON", stack[0].Function)
+	assert.Equal(t, 23, stack[0].Line)
+
+	// Round trip results in the same document
+	remoteData, err := MarshalChain(remote)
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), string(remoteData))
+}
+
+func Test_FromJSONCombination(t *testing.T) {
+	data, err := MarshalChain(Combine(New("e0"), fmt.Errorf("e1: %w", io.ErrUnexpectedEOF)))
+	assert.NoError(t, err)
+	remote, err := FromJSON(data)
+	assert.NoError(t, err)
+
+	assert.EqualError(t, remote, "e0\ne1: unexpected EOF")
+	errs := Uncombine(remote)
+	assert.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], "e0")
+	assert.EqualError(t, errs[1], "e1: unexpected EOF")
+
+	// Not registered sentinels are matched by type name and message
+	assert.True(t, errors.Is(remote, io.ErrUnexpectedEOF))
+	assert.False(t, errors.Is(remote, io.EOF))
+	assert.NotEqual(t, io.ErrUnexpectedEOF, Cause(errs[1]))
+
+	RegisterSentinels(io.ErrUnexpectedEOF)
+	defer UnregisterSentinels(io.ErrUnexpectedEOF)
+	remote, err = FromJSON(data)
+	assert.NoError(t, err)
+	assert.Equal(t, io.ErrUnexpectedEOF, Cause(Uncombine(remote)[1]))
+}
+
+func TestFormatRemote(t *testing.T) {
+	data, err := MarshalChain(Wrap(io.EOF, "wrapped"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := FromJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		error
+		format string
+		want   []string
+	}{{
+		remote,
+		"%s",
+		[]string{"wrapped: EOF"},
+	}, {
+		remote,
+		"%q",
+		[]string{`"wrapped: EOF"`},
+	}, {
+		remote,
+		"%+v",
+		[]string{"EOF", "wrapped",
+			"github.com/domonda/errors.TestFormatRemote\n" +
+				"\t.+/github.com/domonda/errors/remote_test.go:69"},
+	}}
+
+	for i, tt := range tests {
+		testFormatCompleteCompare(t, i, tt.error, tt.format, tt.want, true)
+	}
+}
+
+func Test_FromJSONWithoutMembers(t *testing.T) {
+	remote, err := FromJSON([]byte(`{"type":"x","error":"y","errors":[null]}`))
+	assert.NoError(t, err)
+	assert.EqualError(t, remote, "y")
+	assert.Equal(t, remote, Cause(remote))
+	assert.Len(t, Uncombine(remote), 1)
+
+	// A combination without members must not panic
+	assert.NotPanics(t, func() { Cause(remoteCombination{&remoteError{err: "y"}}) })
+}
@@ -3,38 +3,51 @@ package errors
 import (
 	"fmt"
 	"io"
+	"net/url"
 	"path"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 )
 
-// Frame represents a program counter inside a stack frame.
+// Frame represents a program counter inside a stack frame
+// as returned by runtime.Callers.
+// Frames are resolved with runtime.CallersFrames which
+// accounts for inlined functions.
 type Frame uintptr
 
-// pc returns the program counter for this frame;
-// multiple frames may have the same PC value.
-func (f Frame) pc() uintptr { return uintptr(f) - 1 }
+// resolve returns the symbolic information of the innermost
+// logical frame at this Frame's program counter,
+// or a zero ResolvedFrame if the program counter is invalid.
+func (f Frame) resolve() ResolvedFrame {
+	frames := resolvePC(uintptr(f))
+	if len(frames) == 0 {
+		return ResolvedFrame{}
+	}
+	return frames[0]
+}
 
 // file returns the full path to the file that contains the
 // function for this Frame's pc.
 func (f Frame) file() string {
-	fn := runtime.FuncForPC(f.pc())
-	if fn == nil {
+	file := f.resolve().File
+	if file == "" {
 		return "unknown"
 	}
-	file, _ := fn.FileLine(f.pc())
 	return file
 }
 
 // line returns the line number of source code of the
 // function for this Frame's pc.
 func (f Frame) line() int {
-	fn := runtime.FuncForPC(f.pc())
-	if fn == nil {
-		return 0
-	}
-	_, line := fn.FileLine(f.pc())
-	return line
+	return f.resolve().Line
+}
+
+// name returns the name of this Frame's function
+// or an empty string if the function is unknown.
+func (f Frame) name() string {
+	return f.resolve().Function
 }
 
 // Format formats the frame according to the fmt.Formatter interface.
@@ -46,35 +59,12 @@ func (f Frame) line() int {
 //
 // Format accepts flags that alter the printing of some verbs, as follows:
 //
-//    %+s   function name and path of source file relative to the compile time
-//          GOPATH separated by \n\t (<funcname>\n\t<path>)
+//    %+s   function name and path of source file separated by \n\t
+//          (<funcname>\n\t<path>), the path is trimmed according
+//          to the package level StackFormat
 //    %+v   equivalent to %+s:%d
 func (f Frame) Format(s fmt.State, verb rune) {
-	switch verb {
-	case 's':
-		switch {
-		case s.Flag('+'):
-			pc := f.pc()
-			fn := runtime.FuncForPC(pc)
-			if fn == nil {
-				io.WriteString(s, "unknown")
-			} else {
-				file, _ := fn.FileLine(pc)
-				fmt.Fprintf(s, "%s\n\t%s", fn.Name(), file)
-			}
-		default:
-			io.WriteString(s, path.Base(f.file()))
-		}
-	case 'd':
-		fmt.Fprintf(s, "%d", f.line())
-	case 'n':
-		name := runtime.FuncForPC(f.pc()).Name()
-		io.WriteString(s, funcname(name))
-	case 'v':
-		f.Format(s, 's')
-		io.WriteString(s, ":")
-		f.Format(s, 'd')
-	}
+	f.resolve().Format(s, verb)
 }
 
 // StackTrace is stack of Frames from innermost (newest) to outermost (oldest).
@@ -87,15 +77,15 @@ type StackTrace []Frame
 //
 // Format accepts flags that alter the printing of some verbs, as follows:
 //
-//    %+v   Prints filename, function, and line number for each Frame in the stack.
+//    %+v   Prints filename, function, and line number for each Frame in the stack,
+//          including the frames of inlined function calls.
+//          Frames are hidden according to the package level StackFormat.
 func (st StackTrace) Format(s fmt.State, verb rune) {
 	switch verb {
 	case 'v':
 		switch {
 		case s.Flag('+'):
-			for _, f := range st {
-				fmt.Fprintf(s, "\n%+v", f)
-			}
+			st.Resolve().Format(s, verb)
 		case s.Flag('#'):
 			fmt.Fprintf(s, "%#v", []Frame(st))
 		default:
@@ -106,36 +96,293 @@ func (st StackTrace) Format(s fmt.State, verb rune) {
 	}
 }
 
-// stack represents a stack of program counters.
-type stack []uintptr
+// ResolvedFrame is a stack frame with its symbolic information
+// resolved from the program counter, so it stays meaningful
+// after it was serialized and transferred to another process.
+type ResolvedFrame struct {
+	// Function is the package path-qualified function name
+	Function string `json:"function"`
+	// File is the full path of the source file
+	File string `json:"file"`
+	// Line is the line number in the source file
+	Line int `json:"line"`
+	// Package is the import path of the function's package
+	Package string `json:"package,omitempty"`
+}
+
+func newResolvedFrame(frame runtime.Frame) ResolvedFrame {
+	return ResolvedFrame{
+		Function: frame.Function,
+		File:     frame.File,
+		Line:     frame.Line,
+		Package:  funcpackage(frame.Function),
+	}
+}
+
+// Format formats the frame according to the fmt.Formatter interface
+// with the same verbs and flags as Frame.Format.
+func (f ResolvedFrame) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 's':
+		switch {
+		case f.Function == "" && f.File == "":
+			io.WriteString(s, "unknown")
+		case s.Flag('+'):
+			fmt.Fprintf(s, "%s\n\t%s", f.Function, GetStackFormat().file(f))
+		default:
+			io.WriteString(s, path.Base(f.File))
+		}
+	case 'd':
+		fmt.Fprintf(s, "%d", f.Line)
+	case 'n':
+		io.WriteString(s, funcname(f.Function))
+	case 'v':
+		if s.Flag('+') {
+			f.writeDetailed(s, GetStackFormat())
+			return
+		}
+		f.Format(s, 's')
+		io.WriteString(s, ":")
+		f.Format(s, 'd')
+	}
+}
+
+// writeDetailed writes the frame formatted like %+v
+// without the overhead of the fmt package.
+func (f ResolvedFrame) writeDetailed(w io.Writer, format StackFormat) {
+	if f.Function == "" && f.File == "" {
+		io.WriteString(w, "unknown:0")
+		return
+	}
+	io.WriteString(w, f.Function+"\n\t"+format.file(f)+":"+strconv.Itoa(f.Line))
+}
+
+// ResolvedStackTrace is stack of ResolvedFrames from innermost (newest) to outermost (oldest).
+type ResolvedStackTrace []ResolvedFrame
+
+// StackTrace returns nil because a ResolvedStackTrace
+// has no program counters.
+// It is implemented so that errors carrying a ResolvedStackTrace
+// satisfy the same interfaces as errors carrying a captured stack.
+func (st ResolvedStackTrace) StackTrace() StackTrace {
+	return nil
+}
+
+// ResolvedStackTrace returns st.
+func (st ResolvedStackTrace) ResolvedStackTrace() ResolvedStackTrace {
+	return st
+}
+
+func (st ResolvedStackTrace) hasStack() bool {
+	return len(st) > 0
+}
+
+// Format formats the stack of ResolvedFrames according to the fmt.Formatter interface
+// with the same verbs and flags as StackTrace.Format.
+func (st ResolvedStackTrace) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		switch {
+		case s.Flag('+'):
+			writeFrames(s, st)
+		case s.Flag('#'):
+			fmt.Fprintf(s, "%#v", []ResolvedFrame(st))
+		default:
+			fmt.Fprintf(s, "%v", []ResolvedFrame(st))
+		}
+	case 's':
+		fmt.Fprintf(s, "%s", []ResolvedFrame(st))
+	}
+}
+
+// Resolve returns the symbolic information of the stack's frames.
+// Frames of inlined function calls are reported as separate
+// ResolvedFrames, so the result can have more elements than st.
+func (st StackTrace) Resolve() ResolvedStackTrace {
+	pcs := make([]uintptr, len(st))
+	for i, f := range st {
+		pcs[i] = uintptr(f)
+	}
+	return resolve(pcs)
+}
+
+// resolve returns the symbolic information for the
+// program counters pcs as returned by runtime.Callers.
+func resolve(pcs []uintptr) ResolvedStackTrace {
+	if len(pcs) == 0 {
+		return nil
+	}
+	resolved := make(ResolvedStackTrace, 0, len(pcs))
+	for _, pc := range pcs {
+		resolved = append(resolved, resolvePC(pc)...)
+	}
+	return resolved
+}
+
+// frameCache is the process wide cache of resolvePC.
+// Its size is bounded by the number of call sites in the program.
+var frameCache sync.Map // map[uintptr][]ResolvedFrame
+
+// resolvePC returns the cached symbolic information
+// of the logical frames at the program counter pc
+// as returned by runtime.Callers.
+// Multiple frames are returned for inlined function calls.
+// The returned slice must not be modified.
+func resolvePC(pc uintptr) []ResolvedFrame {
+	if cached, ok := frameCache.Load(pc); ok {
+		return cached.([]ResolvedFrame)
+	}
+
+	var resolved []ResolvedFrame
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" || frame.File != "" {
+			resolved = append(resolved, newResolvedFrame(frame))
+		}
+		if !more {
+			break
+		}
+	}
+	frameCache.Store(pc, resolved)
+	return resolved
+}
+
+// stack represents a stack of program counters
+// that is resolved lazily only once when needed.
+type stack struct {
+	pcs      []uintptr
+	once     sync.Once
+	resolved ResolvedStackTrace
+}
+
+// resolve returns the memoized symbolic information of the stack.
+// The returned slice must not be modified.
+func (s *stack) resolve() ResolvedStackTrace {
+	s.once.Do(func() {
+		s.resolved = resolve(s.pcs)
+	})
+	return s.resolved
+}
 
 func (s *stack) Format(st fmt.State, verb rune) {
 	switch verb {
 	case 'v':
 		switch {
 		case st.Flag('+'):
-			for _, pc := range *s {
-				f := Frame(pc)
-				fmt.Fprintf(st, "\n%+v", f)
-			}
+			s.resolve().Format(st, verb)
 		}
 	}
 }
 
+// formatMerged formats the stack like Format with %+v,
+// but replaces the outermost frames it has in common with
+// the stack trace inner of a wrapped error with a single
+// line like "... 12 more".
+// The innermost frame is always printed.
+func (s *stack) formatMerged(st fmt.State, inner StackTrace) {
+	own := s.pcs
+	common := 0
+	for common < len(own)-1 && common < len(inner) && own[len(own)-1-common] == uintptr(inner[len(inner)-1-common]) {
+		common++
+	}
+	writeFrames(st, resolve(own[:len(own)-common]))
+	if common > 0 {
+		fmt.Fprintf(st, "\n\t... %d more", common)
+	}
+}
+
+func (s *stack) hasStack() bool {
+	return len(s.pcs) > 0
+}
+
 func (s *stack) StackTrace() StackTrace {
-	f := make([]Frame, len(*s))
+	f := make([]Frame, len(s.pcs))
 	for i := 0; i < len(f); i++ {
-		f[i] = Frame((*s)[i])
+		f[i] = Frame(s.pcs[i])
 	}
 	return f
 }
 
-func callers(skipExtra int) *stack {
-	const depth = 32
-	var pcs [depth]uintptr
-	n := runtime.Callers(3+skipExtra, pcs[:])
-	var st stack = pcs[0:n]
-	return &st
+func (s *stack) ResolvedStackTrace() ResolvedStackTrace {
+	resolved := s.resolve()
+	if resolved == nil {
+		return nil
+	}
+	return append(make(ResolvedStackTrace, 0, len(resolved)), resolved...)
+}
+
+// callStack is implemented by *stack holding the program counters
+// of a stack captured in this process and by ResolvedStackTrace
+// holding symbolic frames, for example received from another process.
+type callStack interface {
+	fmt.Formatter
+	StackTrace() StackTrace
+	ResolvedStackTrace() ResolvedStackTrace
+	hasStack() bool
+}
+
+// callers captures the stack of the caller of the function calling callers
+// minus skipExtra frames according to the package level stack policy.
+// The chain of cause is checked for existing stack traces
+// depending on the policy, cause may be nil.
+func callers(skipExtra int, cause error) *stack {
+	return GetStackPolicy().callers(skipExtra+1, cause)
+}
+
+// causeStackTrace returns the first non empty stack trace
+// captured in this process in the chain of causes of err
+// or nil if there is none.
+func causeStackTrace(err error) StackTrace {
+	type causer interface {
+		Cause() error
+	}
+	type wrapper interface {
+		Unwrap() error
+	}
+	type stackTracer interface {
+		StackTrace() StackTrace
+	}
+
+	for err != nil {
+		if s, ok := err.(stackTracer); ok {
+			if st := s.StackTrace(); len(st) > 0 {
+				return st
+			}
+		}
+		switch e := err.(type) {
+		case causer:
+			err = e.Cause()
+		case wrapper:
+			err = e.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// funcpackage returns the package import path of a function's name reported by func.Name().
+// The runtime escapes dots in the last element of the import path
+// like in "gopkg.in/yaml%2ev2.Unmarshal", those are unescaped.
+func funcpackage(name string) string {
+	if i := strings.IndexByte(name, '['); i != -1 {
+		// Remove type parameters of generic functions
+		// that can contain import paths
+		name = name[:i]
+	}
+	i := strings.LastIndex(name, "/")
+	j := strings.Index(name[i+1:], ".")
+	if j < 0 {
+		return ""
+	}
+	pkg := name[:i+1+j]
+	if strings.IndexByte(pkg, '%') != -1 {
+		if unescaped, err := url.PathUnescape(pkg); err == nil {
+			pkg = unescaped
+		}
+	}
+	return pkg
 }
 
 // funcname removes the path prefix component of a function's name reported by func.Name().
@@ -23,7 +23,7 @@ func TestFrameLine(t *testing.T) {
 		20,
 	}, {
 		func() Frame {
-			var pc, _, _, _ = runtime.Caller(1)
+			var pc = callersPC(1)
 			return Frame(pc)
 		}(),
 		28,
@@ -178,7 +178,7 @@ func TestStackTrace(t *testing.T) {
 				return Errorf("hello %s", fmt.Sprintf("world"))
 			}()
 		}()), []string{
-			`github.com/domonda/errors.(func·010|TestStackTrace.func2.1)` +
+			`github.com/domonda/errors.(func·010|TestStackTrace.func2.1|TestStackTrace.func2.func1)` +
 				"\n\t.+/github.com/domonda/errors/stack_test.go:178", // this is the stack of Errorf
 			`github.com/domonda/errors.(func·011|TestStackTrace.func2)` +
 				"\n\t.+/github.com/domonda/errors/stack_test.go:179", // this is the stack of Errorf's caller
@@ -205,7 +205,7 @@ func stackTrace() StackTrace {
 	const depth = 8
 	var pcs [depth]uintptr
 	n := runtime.Callers(1, pcs[:])
-	var st stack = pcs[0:n]
+	st := stack{pcs: pcs[0:n]}
 	return st.StackTrace()
 }
 
@@ -272,3 +272,155 @@ func TestStackTraceFormat(t *testing.T) {
 		testFormatRegexp(t, i, tt.StackTrace, tt.format, tt.want)
 	}
 }
+
+func TestStackTraceResolve(t *testing.T) {
+	if got := StackTrace(nil).Resolve(); got != nil {
+		t.Errorf("StackTrace(nil).Resolve(): want: nil, got: %#v", got)
+	}
+
+	resolved := stackTrace()[:2].Resolve()
+	want := ResolvedStackTrace{{
+		Function: "github.com/domonda/errors.stackTrace",
+		Line:     207,
+		Package:  "github.com/domonda/errors",
+	}, {
+		Function: "github.com/domonda/errors.TestStackTraceResolve",
+		Line:     281,
+		Package:  "github.com/domonda/errors",
+	}}
+	if len(resolved) != len(want) {
+		t.Fatalf("len(Resolve()): want: %d, got: %d", len(want), len(resolved))
+	}
+	for i, w := range want {
+		got := resolved[i]
+		if got.Function != w.Function || got.Line != w.Line || got.Package != w.Package {
+			t.Errorf("Resolve()[%d]: want: %#v, got: %#v", i, w, got)
+		}
+		testFormatRegexp(t, i, got, "%+v", w.Function+"\n\t.+/github.com/domonda/errors/stack_test.go:"+fmt.Sprint(w.Line))
+	}
+
+	err := WithResolvedStackTrace(Const("EOF"), resolved)
+	testFormatRegexp(t, 0, err, "%+v", "EOF\n"+
+		"github.com/domonda/errors.stackTrace\n"+
+		"\t.+/github.com/domonda/errors/stack_test.go:207\n"+
+		"github.com/domonda/errors.TestStackTraceResolve\n"+
+		"\t.+/github.com/domonda/errors/stack_test.go:281")
+}
+
+// callersPC returns the program counter of the frame skip levels
+// above the caller of callersPC as returned by runtime.Callers,
+// which differs from the program counter returned by runtime.Caller
+// for inlined functions.
+func callersPC(skip int) uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(2+skip, pcs[:])
+	return pcs[0]
+}
+
+func TestFuncpackage(t *testing.T) {
+	tests := map[string]string{
+		"":                                   "",
+		"main.main":                          "main",
+		"runtime.goexit":                     "runtime",
+		"github.com/domonda/errors.(*X).ptr": "github.com/domonda/errors",
+		"github.com/domonda/errors.TestStackTrace.func2.1": "github.com/domonda/errors",
+		"gopkg.in/yaml%2ev2.Unmarshal":                     "gopkg.in/yaml.v2",
+	}
+	for name, want := range tests {
+		if got := funcpackage(name); got != want {
+			t.Errorf("funcpackage(%q): want: %q, got: %q", name, want, got)
+		}
+	}
+}
+
+// inlinedNew and inlinedWrap are small enough
+// to be inlined into their callers by the compiler.
+func inlinedNew() error {
+	return New("inlined")
+}
+
+func inlinedWrap() error {
+	return inlinedNew()
+}
+
+func TestStackTraceInlined(t *testing.T) {
+	st := inlinedWrap().(interface{ StackTrace() StackTrace }).StackTrace()
+
+	testFormatRegexp(t, 0, st[0], "%+v",
+		"github.com/domonda/errors.inlinedNew\n"+
+			"\t.+/github.com/domonda/errors/stack_test.go:339")
+	testFormatRegexp(t, 1, st[1], "%+v",
+		"github.com/domonda/errors.inlinedWrap\n"+
+			"\t.+/github.com/domonda/errors/stack_test.go:343")
+	testFormatRegexp(t, 2, st[2], "%+v",
+		"github.com/domonda/errors.TestStackTraceInlined\n"+
+			"\t.+/github.com/domonda/errors/stack_test.go:347")
+	testFormatRegexp(t, 3, st, "%+v", "\n"+
+		"github.com/domonda/errors.inlinedNew\n"+
+		"\t.+/github.com/domonda/errors/stack_test.go:339\n"+
+		"github.com/domonda/errors.inlinedWrap\n"+
+		"\t.+/github.com/domonda/errors/stack_test.go:343\n"+
+		"github.com/domonda/errors.TestStackTraceInlined\n"+
+		"\t.+/github.com/domonda/errors/stack_test.go:347")
+
+	resolved := st.Resolve()
+	for i, want := range []string{"inlinedNew", "inlinedWrap", "TestStackTraceInlined"} {
+		if resolved[i].Function != "github.com/domonda/errors."+want {
+			t.Errorf("Resolve()[%d].Function: want: %s, got: %s", i, want, resolved[i].Function)
+		}
+	}
+}
+
+func TestFormatMergedStacks(t *testing.T) {
+	always := StackPolicy{Mode: StackAlways}
+	err := always.New("error")
+	err = always.Wrap(err, "wrapped")
+
+	testFormatRegexp(t, 0, err, "%+v", "error\n"+
+		"github.com/domonda/errors.TestFormatMergedStacks\n"+
+		"\t.+/github.com/domonda/errors/stack_test.go:376\n"+
+		"testing.tRunner\n"+
+		"\t.+\n"+
+		"runtime.goexit\n"+
+		"\t.+\n"+
+		"wrapped\n"+
+		"github.com/domonda/errors.TestFormatMergedStacks\n"+
+		"\t.+/github.com/domonda/errors/stack_test.go:377\n"+
+		"\t\\.\\.\\. 2 more$")
+
+	// The default policy captures only the caller frame
+	// when wrapping an error with a stack trace
+	err = Wrap(New("error"), "wrapped")
+	testFormatRegexp(t, 1, err, "%+v", "error\n"+
+		"github.com/domonda/errors.TestFormatMergedStacks\n"+
+		"\t.+/github.com/domonda/errors/stack_test.go:393\n"+
+		"testing.tRunner\n"+
+		"\t.+\n"+
+		"runtime.goexit\n"+
+		"\t.+\n"+
+		"wrapped\n"+
+		"github.com/domonda/errors.TestFormatMergedStacks\n"+
+		"\t.+/github.com/domonda/errors/stack_test.go:393$")
+	lines := 1
+	for _, c := range fmt.Sprintf("%+v", err) {
+		if c == '\n' {
+			lines++
+		}
+	}
+	if lines != 10 {
+		t.Errorf("%%+v: want: 10 lines, got: %d", lines)
+	}
+}
+
+func TestFuncpackageGeneric(t *testing.T) {
+	tests := map[string]string{
+		"github.com/example/lib.Map[...]":                                                "github.com/example/lib",
+		"github.com/example/lib.Map[go.shape.struct { github.com/example/other.X int }]": "github.com/example/lib",
+		"gopkg.in/yaml%2ev2.Map[go.shape.*gopkg.in/yaml%2ev2.Node]":                      "gopkg.in/yaml.v2",
+	}
+	for name, want := range tests {
+		if got := funcpackage(name); got != want {
+			t.Errorf("funcpackage(%q): want: %q, got: %q", name, want, got)
+		}
+	}
+}
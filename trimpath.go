@@ -0,0 +1,140 @@
+package errors
+
+import (
+	"path"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// PathTrimmer returns the source file path of a frame
+// that is printed when formatting stack traces with %+v
+// to avoid leaking the directory layout of the build machine
+// and to make stack traces comparable between builds.
+type PathTrimmer func(frame ResolvedFrame) string
+
+var (
+	// TrimGOPATH trims the GOPATH, GOMODCACHE, and GOROOT directories
+	// of source file paths so that they start with the import path
+	// of their package, or with the module path and version
+	// for files from the module cache.
+	// The directories are detected by the package import path
+	// and the pkg/mod directory convention, so it also works
+	// for binaries built on another machine.
+	TrimGOPATH PathTrimmer = trimGOPATH
+
+	// TrimModuleRoot trims the source file paths of the main module
+	// so that they are relative to the module root directory,
+	// including the files of the main package.
+	// The main module is determined with debug.ReadBuildInfo
+	// and its root directory from the first trimmed frame
+	// of one of its packages.
+	// Paths of other files are trimmed like TrimGOPATH.
+	TrimModuleRoot PathTrimmer = trimModuleRoot
+)
+
+// TrimPrefixes returns a PathTrimmer that replaces
+// the longest of the prefixes that a source file path
+// starts with by the prefix' value in the passed map.
+// Example:
+//     errors.TrimPrefixes(map[string]string{
+//         "/home/ci/go/pkg/mod/": "",
+//         "/builds/project/":     "project/",
+//     })
+func TrimPrefixes(replacements map[string]string) PathTrimmer {
+	prefixes := make([]string, 0, len(replacements))
+	copied := make(map[string]string, len(replacements))
+	for prefix, replacement := range replacements {
+		prefixes = append(prefixes, prefix)
+		copied[prefix] = replacement
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	return func(frame ResolvedFrame) string {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(frame.File, prefix) {
+				return copied[prefix] + frame.File[len(prefix):]
+			}
+		}
+		return frame.File
+	}
+}
+
+func trimGOPATH(frame ResolvedFrame) string {
+	const modDir = "/pkg/mod/"
+	if i := strings.LastIndex(frame.File, modDir); i != -1 {
+		return frame.File[i+len(modDir):]
+	}
+	if frame.Package != "" {
+		pkgFile := frame.Package + "/" + path.Base(frame.File)
+		if strings.HasSuffix(frame.File, "/"+pkgFile) {
+			return pkgFile
+		}
+	}
+	return frame.File
+}
+
+// mainModule is the main module of the binary
+// as reported by debug.ReadBuildInfo.
+type mainModule struct {
+	path    string       // module path
+	mainPkg string       // import path of the main package
+	dir     atomic.Value // string, root directory derived from a frame
+}
+
+var currentMainModule atomic.Value // *mainModule
+
+func getMainModule() *mainModule {
+	if m, ok := currentMainModule.Load().(*mainModule); ok {
+		return m
+	}
+	m := new(mainModule)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		m.path = info.Main.Path
+		m.mainPkg = info.Path
+	}
+	currentMainModule.Store(m)
+	return m
+}
+
+// rootDir returns the root directory of the module in source file paths
+// or an empty string if it is not known yet and can't be derived
+// from frame because it is not a frame of a package of the module.
+// The runtime reports the package of the main package's frames as "main",
+// so the import path of the main package from the build info is used for them.
+func (m *mainModule) rootDir(frame ResolvedFrame) string {
+	if dir, _ := m.dir.Load().(string); dir != "" {
+		return dir
+	}
+	pkg := frame.Package
+	if pkg == "main" {
+		pkg = m.mainPkg
+	}
+	var rel string
+	switch {
+	case m.path == "" || pkg == "":
+		return ""
+	case pkg == m.path:
+		rel = ""
+	case strings.HasPrefix(pkg, m.path+"/"):
+		rel = pkg[len(m.path):]
+	default:
+		return ""
+	}
+	dir := path.Dir(frame.File)
+	if !strings.HasSuffix(dir, rel) || len(dir) == len(rel) {
+		return ""
+	}
+	dir = dir[:len(dir)-len(rel)]
+	m.dir.Store(dir)
+	return dir
+}
+
+func trimModuleRoot(frame ResolvedFrame) string {
+	dir := getMainModule().rootDir(frame)
+	if dir != "" && strings.HasPrefix(frame.File, dir+"/") {
+		return frame.File[len(dir)+1:]
+	}
+	return trimGOPATH(frame)
+}
@@ -0,0 +1,114 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrimGOPATH(t *testing.T) {
+	tests := []struct {
+		frame ResolvedFrame
+		want  string
+	}{
+		{ResolvedFrame{}, ""},
+		{
+			ResolvedFrame{File: "/home/user/go/pkg/mod/github.com/example/lib@v1.2.3/lib.go", Package: "github.com/example/lib"},
+			"github.com/example/lib@v1.2.3/lib.go",
+		},
+		{
+			ResolvedFrame{File: "/home/user/go/src/github.com/example/lib/sub/sub.go", Package: "github.com/example/lib/sub"},
+			"github.com/example/lib/sub/sub.go",
+		},
+		{
+			ResolvedFrame{File: "/usr/local/go/src/net/http/server.go", Package: "net/http"},
+			"net/http/server.go",
+		},
+		{
+			ResolvedFrame{File: "/build/cmd/server/main.go", Package: "main"},
+			"/build/cmd/server/main.go",
+		},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, TrimGOPATH(tt.frame), "TrimGOPATH(%#v)", tt.frame)
+	}
+}
+
+func TestTrimModuleRoot(t *testing.T) {
+	defer currentMainModule.Store(getMainModule())
+
+	tests := []struct {
+		frame ResolvedFrame
+		want  string
+	}{
+		{
+			ResolvedFrame{File: "/builds/app/app.go", Package: "github.com/example/app"},
+			"app.go",
+		},
+		{
+			ResolvedFrame{File: "/builds/app/internal/db/db.go", Package: "github.com/example/app/internal/db"},
+			"internal/db/db.go",
+		},
+		{
+			ResolvedFrame{File: "/builds/app/cmd/server/main.go", Package: "main"},
+			"cmd/server/main.go",
+		},
+		{
+			ResolvedFrame{File: "/builds/app2/app2.go", Package: "github.com/example/app2"},
+			"/builds/app2/app2.go",
+		},
+		{
+			ResolvedFrame{File: "/root/go/pkg/mod/github.com/example/lib@v1.2.3/lib.go", Package: "github.com/example/lib"},
+			"github.com/example/lib@v1.2.3/lib.go",
+		},
+	}
+	for _, tt := range tests {
+		currentMainModule.Store(&mainModule{path: "github.com/example/app", mainPkg: "github.com/example/app/cmd/server"})
+		assert.Equal(t, tt.want, TrimModuleRoot(tt.frame), "TrimModuleRoot(%#v)", tt.frame)
+	}
+
+	// The root directory derived from a frame of the main package
+	// is used to trim the frames of the other packages
+	currentMainModule.Store(&mainModule{path: "github.com/example/app", mainPkg: "github.com/example/app/cmd/server"})
+	assert.Equal(t, "cmd/server/main.go", TrimModuleRoot(ResolvedFrame{File: "/builds/app/cmd/server/main.go", Package: "main"}))
+	assert.Equal(t, "internal/db/db_test.go", TrimModuleRoot(ResolvedFrame{File: "/builds/app/internal/db/db_test.go", Package: "github.com/example/app/internal/db_test"}))
+
+	// The main package of the module root directory
+	currentMainModule.Store(&mainModule{path: "github.com/example/app", mainPkg: "github.com/example/app"})
+	assert.Equal(t, "main.go", TrimModuleRoot(ResolvedFrame{File: "/builds/app/main.go", Package: "main"}))
+
+	// Not a module build like go run main.go
+	currentMainModule.Store(&mainModule{})
+	assert.Equal(t, "/builds/app/main.go", TrimModuleRoot(ResolvedFrame{File: "/builds/app/main.go", Package: "main"}))
+}
+
+func TestTrimPrefixes(t *testing.T) {
+	trim := TrimPrefixes(map[string]string{
+		"/builds/":         "",
+		"/builds/project/": "project/",
+	})
+	assert.Equal(t, "project/main.go", trim(ResolvedFrame{File: "/builds/project/main.go"}))
+	assert.Equal(t, "other/main.go", trim(ResolvedFrame{File: "/builds/other/main.go"}))
+	assert.Equal(t, "/src/main.go", trim(ResolvedFrame{File: "/src/main.go"}))
+}
+
+func TestFormatTrimmedPath(t *testing.T) {
+	defer SetStackFormat(SetStackFormat(StackFormat{TrimPath: TrimGOPATH}))
+
+	frame := ResolvedFrame{
+		Function: "github.com/example/lib.Func",
+		File:     "/home/user/go/pkg/mod/github.com/example/lib@v1.2.3/lib.go",
+		Line:     7,
+		Package:  "github.com/example/lib",
+	}
+	want := "github.com/example/lib.Func\n\tgithub.com/example/lib@v1.2.3/lib.go:7"
+	assert.Equal(t, want, fmt.Sprintf("%+v", frame))
+	assert.Equal(t, "\n"+want, fmt.Sprintf("%+v", ResolvedStackTrace{frame}))
+	assert.Equal(t, "lib.go", fmt.Sprintf("%s", frame))
+
+	// Paths in JSON are not trimmed
+	data, err := MarshalChain(WithResolvedStackTrace(Const("x"), ResolvedStackTrace{frame}))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), frame.File)
+}
@@ -0,0 +1,142 @@
+package errors
+
+import "reflect"
+
+// Walk traverses the tree of err and its causes and combined errors
+// in depth-first order and calls fn for every error with its depth,
+// starting with err itself at depth zero.
+// If fn returns false, then the causes and combined errors
+// of the error passed to fn are not walked.
+//
+// The children of an error are the results of the first
+// of the following methods that it implements:
+//     Errors() []error
+//     Unwrap() []error
+//     Cause() error
+//     Unwrap() error
+// Nil children are skipped, and so are errors that are
+// already being walked as ancestors to protect against cycles.
+// Errors that can't be identified as ancestors because
+// they are neither pointers nor comparable are not walked
+// deeper than MaxWalkDepth.
+// If err is nil, fn is not called.
+func Walk(err error, fn func(e error, depth int) bool) {
+	walk(err, func(e error, _ []error, depth int) bool { return fn(e, depth) }, nil)
+}
+
+// MaxWalkDepth is the maximum depth of errors passed by Walk.
+// It limits the traversal of cycles that can't be detected.
+const MaxWalkDepth = 10000
+
+// walk calls fn with the children of err
+// so that they don't have to be determined twice.
+func walk(err error, fn func(e error, children []error, depth int) bool, ancestors []error) {
+	if err == nil || len(ancestors) > MaxWalkDepth || isAncestor(err, ancestors) {
+		return
+	}
+	kids := children(err)
+	if !fn(err, kids, len(ancestors)) {
+		return
+	}
+	ancestors = append(ancestors, err)
+	for _, child := range kids {
+		walk(child, fn, ancestors)
+	}
+}
+
+// RootCauses returns all leaf errors of the tree of err
+// and its causes and combined errors as traversed by Walk.
+// In contrast to Cause, which follows only the first error
+// of a combination, the causes of all combined errors are returned.
+// If err is nil, nil is returned.
+func RootCauses(err error) []error {
+	var causes []error
+	walk(err, func(e error, kids []error, depth int) bool {
+		if len(kids) == 0 {
+			causes = append(causes, e)
+		}
+		return true
+	}, nil)
+	return causes
+}
+
+// children returns the non nil causes or combined errors of err.
+func children(err error) []error {
+	type causer interface {
+		Cause() error
+	}
+	type wrapper interface {
+		Unwrap() error
+	}
+
+	var errs []error
+	switch e := err.(type) {
+	case multiError:
+		errs = e.Errors()
+	case multiWrapper:
+		errs = e.Unwrap()
+	case causer:
+		errs = []error{e.Cause()}
+	case wrapper:
+		errs = []error{e.Unwrap()}
+	}
+	for _, child := range errs {
+		if child == nil {
+			// Filter only if necessary
+			// to avoid allocations for the common case
+			return nonNil(errs)
+		}
+	}
+	return errs
+}
+
+func nonNil(errs []error) []error {
+	var result []error
+	for _, err := range errs {
+		if err != nil {
+			result = append(result, err)
+		}
+	}
+	return result
+}
+
+// isAncestor returns if err is identical to one of the ancestors.
+func isAncestor(err error, ancestors []error) bool {
+	for _, ancestor := range ancestors {
+		if identical(err, ancestor) {
+			return true
+		}
+	}
+	return false
+}
+
+// identical returns if a and b are the same error.
+// Errors of pointer, map, channel, function, and slice types
+// are identical if they have the same type and point to the same data,
+// errors of other comparable types if they are equal compared with ==.
+// Errors of other types that are not comparable are never identical,
+// neither are errors that panic when compared because their
+// comparable type holds not comparable values in interface fields.
+func identical(a, b error) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() || va.Type() != vb.Type() {
+		return false
+	}
+	switch va.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return va.Pointer() == vb.Pointer()
+	case reflect.Slice:
+		return va.Pointer() == vb.Pointer() && va.Len() == vb.Len()
+	}
+	return va.Type().Comparable() && equal(a, b)
+}
+
+// equal returns a == b or false if the comparison panics.
+func equal(a, b error) (eq bool) {
+	defer func() {
+		if recover() != nil {
+			eq = false
+		}
+	}()
+	return a == b
+}
@@ -0,0 +1,129 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// cyclicError is its own cause
+type cyclicError struct {
+	cause error
+}
+
+func (e *cyclicError) Error() string { return "cyclic" }
+func (e *cyclicError) Cause() error  { return e.cause }
+
+// sliceError is not comparable
+type sliceError []error
+
+func (e sliceError) Error() string   { return "slice" }
+func (e sliceError) Errors() []error { return e }
+
+func Test_RootCauses(t *testing.T) {
+	var (
+		e0 = Const("e0")
+		e1 = Const("e1")
+		e2 = Const("e2")
+		e3 = Const("e3")
+	)
+
+	assert.Nil(t, RootCauses(nil))
+	assert.Equal(t, []error{e0}, RootCauses(e0))
+	assert.Equal(t, []error{e0}, RootCauses(Wrap(e0, "wrapped")))
+	assert.Equal(t, []error{e0, e1}, RootCauses(Combine(e0, e1)))
+
+	err := Combine(
+		Wrap(Combine(e0, WithField(e1, "key", "value")), "inner"),
+		fmt.Errorf("join: %w", errors.Join(e2, nil, Wrap(e3, "e3"))),
+		io.EOF,
+	)
+	assert.Equal(t, []error{e0, e1, e2, e3, io.EOF}, RootCauses(err))
+	assert.Equal(t, e0, Cause(err))
+
+	// The same error in different branches is not a cycle
+	assert.Equal(t, []error{e0, e0}, RootCauses(errors.Join(e0, Wrap(e0, "again"))))
+
+	assert.Equal(t, []error{e0, e1}, RootCauses(sliceError{e0, sliceError{e1}}))
+}
+
+func Test_Walk(t *testing.T) {
+	inner := Combine(Const("e0"), Const("e1"))
+	err := WithMessage(inner, "message")
+
+	var walked []string
+	Walk(err, func(e error, depth int) bool {
+		walked = append(walked, fmt.Sprintf("%d:%T", depth, e))
+		return true
+	})
+	assert.Equal(t, []string{"0:*errors.withMessage", "1:*errors.combination", "2:errors.Const", "2:errors.Const"}, walked)
+
+	// Returning false skips the children
+	walked = nil
+	Walk(err, func(e error, depth int) bool {
+		walked = append(walked, fmt.Sprintf("%d:%T", depth, e))
+		return e != inner
+	})
+	assert.Equal(t, []string{"0:*errors.withMessage", "1:*errors.combination"}, walked)
+
+	Walk(nil, func(e error, depth int) bool {
+		t.Fatal("called for nil error")
+		return true
+	})
+}
+
+func Test_WalkCycle(t *testing.T) {
+	cyclic := &cyclicError{}
+	cyclic.cause = Combine(io.EOF, WithMessage(cyclic, "cycle"))
+
+	depths := make(map[int]int)
+	Walk(cyclic, func(e error, depth int) bool {
+		depths[depth]++
+		return true
+	})
+	assert.Equal(t, map[int]int{0: 1, 1: 1, 2: 2}, depths)
+	assert.Equal(t, []error{io.EOF}, RootCauses(cyclic))
+}
+
+// mapError is not comparable
+type mapError map[string]error
+
+func (e mapError) Error() string { return "map" }
+func (e mapError) Cause() error  { return e["cause"] }
+
+// structError is not comparable and can't be
+// identified, its cycle is limited by MaxWalkDepth
+type structError struct {
+	causes []error
+}
+
+func (e structError) Error() string { return "struct" }
+func (e structError) Cause() error  { return e.causes[0] }
+
+func Test_WalkCycleNotComparable(t *testing.T) {
+	m := mapError{}
+	m["cause"] = WithMessage(m, "cycle")
+	count := 0
+	Walk(m, func(e error, depth int) bool {
+		count++
+		return true
+	})
+	assert.Equal(t, 2, count)
+	assert.Nil(t, RootCauses(m))
+
+	s := sliceError{io.EOF, nil}
+	s[1] = s
+	assert.Equal(t, []error{io.EOF}, RootCauses(s))
+
+	st := structError{causes: make([]error, 1)}
+	st.causes[0] = st
+	maxDepth := 0
+	Walk(st, func(e error, depth int) bool {
+		maxDepth = depth
+		return true
+	})
+	assert.Equal(t, MaxWalkDepth, maxDepth)
+}
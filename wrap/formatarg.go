@@ -1,9 +1,10 @@
 package wrap
 
 import (
-	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
 	reflection "github.com/ungerik/go-reflection"
 )
@@ -12,10 +13,22 @@ func formatArg(arg interface{}) string {
 	if arg == nil {
 		return "<nil>"
 	}
+	if named, ok := arg.(NamedArg); ok {
+		if isRedactedName(named.Name) {
+			return Redacted
+		}
+		return formatArg(named.Value)
+	}
 	v := reflect.ValueOf(arg)
 	if reflection.IsNil(v) {
 		return "<nil>"
 	}
+	if isRedactedType(v.Type()) {
+		return Redacted
+	}
+	if r, ok := redactorOf(v); ok {
+		return fmt.Sprintf("%q", r.Redact())
+	}
 
 	switch a := arg.(type) {
 	case error:
@@ -31,7 +44,7 @@ func formatArg(arg interface{}) string {
 
 	if v.Kind() == reflect.Ptr {
 		switch v.Elem().Kind() {
-		case reflect.Struct:
+		case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
 			// handle futher down
 
 		case reflect.Func:
@@ -57,12 +70,15 @@ func formatArg(arg interface{}) string {
 		return "<chan>"
 
 	case reflect.Struct:
-		bytes, err := json.Marshal(arg)
+		bytes, err := marshalRedacted(v)
 		if err != nil {
 			return t.Name() + "marshaling error"
 		}
 		return t.Name() + string(bytes)
 
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return formatContainer(reflect.Indirect(v), make(map[visitKey]bool))
+
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		// "%#v" would return hex literal
 		return fmt.Sprintf("%v", arg)
@@ -70,3 +86,87 @@ func formatArg(arg interface{}) string {
 
 	return fmt.Sprintf("%#v", arg)
 }
+
+// formatContainer formats the slice, array, or map v like "%#v"
+// but with elements formatted by formatArg, so that they are redacted,
+// and with map values redacted if their keys match
+// one of the rules registered with RedactNames.
+func formatContainer(v reflect.Value, visiting map[visitKey]bool) string {
+	if !needsRedaction(v.Type(), make(map[reflect.Type]bool)) {
+		return fmt.Sprintf("%#v", v.Interface())
+	}
+	if v.Kind() != reflect.Array && v.IsNil() {
+		return v.Type().String() + "(nil)"
+	}
+	if v.Kind() != reflect.Array {
+		key := visitKey{ptr: v.Pointer(), typ: v.Type(), len: v.Len()}
+		if visiting[key] {
+			return v.Type().String() + "{...}"
+		}
+		visiting[key] = true
+		defer delete(visiting, key)
+	}
+
+	var b strings.Builder
+	b.WriteString(v.Type().String())
+	b.WriteByte('{')
+	if v.Kind() == reflect.Map {
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return lessMapKey(keys[i], keys[j]) })
+		for i, key := range keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(formatElem(key, visiting))
+			b.WriteByte(':')
+			if key.Kind() == reflect.String && isRedactedName(key.String()) {
+				b.WriteString(Redacted)
+				continue
+			}
+			b.WriteString(formatElem(v.MapIndex(key), visiting))
+		}
+	} else {
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(formatElem(v.Index(i), visiting))
+		}
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// formatElem formats an element of a container with formatArg
+// or with formatContainer for nested containers.
+func formatElem(v reflect.Value, visiting map[visitKey]bool) string {
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if !isRedactedType(v.Type()) && !isRedactor(v.Type()) && v.Type() != reflect.TypeOf([]byte(nil)) {
+			return formatContainer(v, visiting)
+		}
+	}
+	if !v.IsValid() || !v.CanInterface() {
+		return "<nil>"
+	}
+	return formatArg(v.Interface())
+}
+
+// lessMapKey sorts map keys of basic kinds by their values
+// and other keys by their formatted strings.
+func lessMapKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
@@ -0,0 +1,184 @@
+package wrap
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// jsonField is a struct field that is marshaled by encoding/json.
+type jsonField struct {
+	name      string
+	tagged    bool
+	index     []int
+	field     reflect.StructField
+	omitEmpty bool
+	quoted    bool
+}
+
+// jsonFields returns the fields of the struct type t
+// that encoding/json marshals, in the same order
+// and with the same rules for promoted fields of embedded structs:
+// Fields with less embedding depth hide deeper fields with the same name,
+// at the same depth fields with a JSON tag name hide fields without,
+// and other fields with the same name at the same depth are omitted.
+func jsonFields(t reflect.Type) []jsonField {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var (
+		fields    []jsonField
+		current   []embedded
+		next      = []embedded{{typ: t}}
+		count     map[reflect.Type]int
+		nextCount = map[reflect.Type]int{}
+		visited   = map[reflect.Type]bool{}
+	)
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				fieldType := sf.Type
+				if fieldType.Name() == "" && fieldType.Kind() == reflect.Ptr {
+					fieldType = fieldType.Elem()
+				}
+				if sf.Anonymous {
+					if sf.PkgPath != "" && fieldType.Kind() != reflect.Struct {
+						continue
+					}
+				} else if sf.PkgPath != "" {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, options := tag, ""
+				if comma := strings.IndexByte(tag, ','); comma != -1 {
+					name, options = tag[:comma], tag[comma:]
+				}
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+
+				if name == "" && sf.Anonymous && fieldType.Kind() == reflect.Struct {
+					// Fields of embedded structs are promoted
+					nextCount[fieldType]++
+					if nextCount[fieldType] == 1 {
+						next = append(next, embedded{typ: fieldType, index: index})
+					}
+					continue
+				}
+
+				f := jsonField{
+					name:      name,
+					tagged:    name != "",
+					index:     index,
+					field:     sf,
+					omitEmpty: strings.Contains(options, ",omitempty"),
+					quoted:    strings.Contains(options, ",string") && isQuotable(fieldType.Kind()),
+				}
+				if f.name == "" {
+					f.name = sf.Name
+				}
+				fields = append(fields, f)
+				if count[e.typ] > 1 {
+					// The same struct is embedded more than once
+					// at the same depth, so its fields hide each other
+					fields = append(fields, f)
+				}
+			}
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i], fields[j]
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		if len(a.index) != len(b.index) {
+			return len(a.index) < len(b.index)
+		}
+		if a.tagged != b.tagged {
+			return a.tagged
+		}
+		return lessIndex(a.index, b.index)
+	})
+
+	// Keep the dominant field of every name
+	dominant := fields[:0]
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		if j-i == 1 ||
+			len(fields[i].index) < len(fields[i+1].index) ||
+			fields[i].tagged && !fields[i+1].tagged {
+			dominant = append(dominant, fields[i])
+		}
+		i = j
+	}
+
+	sort.Slice(dominant, func(i, j int) bool {
+		return lessIndex(dominant[i].index, dominant[j].index)
+	})
+	return dominant
+}
+
+func lessIndex(a, b []int) bool {
+	for k, x := range a {
+		if k >= len(b) {
+			return false
+		}
+		if x != b[k] {
+			return x < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// isQuotable returns if the string option
+// of a JSON tag applies to values of kind k.
+func isQuotable(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return true
+	}
+	return false
+}
+
+// isEmptyValue returns if v is omitted from JSON
+// by the omitempty option of a JSON tag.
+// In contrast to reflect.Value.IsZero structs are never empty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
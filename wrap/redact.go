@@ -0,0 +1,477 @@
+package wrap
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	reflection "github.com/ungerik/go-reflection"
+)
+
+// Redacted replaces the values of redacted arguments
+// and struct fields in formatted call signatures.
+const Redacted = "***"
+
+// RedactTag is the struct tag key for the value "redact"
+// that redacts a struct field in formatted call signatures.
+// Example:
+//     type Login struct {
+//         User     string
+//         Password string `errors:"redact"`
+//     }
+const RedactTag = "errors"
+
+// Redactor can be implemented by types to return a representation
+// of their values that is safe to be used in error messages and logs,
+// for example an IBAN with only the last digits visible.
+type Redactor interface {
+	Redact() string
+}
+
+// NamedArg is a function argument with a parameter name
+// returned by Named that is redacted if the name matches
+// one of the rules registered with RedactNames.
+type NamedArg struct {
+	Name  string
+	Value interface{}
+}
+
+// Named returns a function argument with a parameter name
+// that is redacted if the name matches one of the rules
+// registered with RedactNames.
+// Example:
+//     defer wrap.ResultError(&err, "login", user, wrap.Named("password", password))
+func Named(name string, value interface{}) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
+
+var (
+	redactedTypes = make(map[reflect.Type]struct{})
+	redactedNames = []string{"password", "passwd", "secret", "token", "apikey", "api_key", "iban"}
+	redactMtx     sync.RWMutex
+)
+
+// RedactTypes registers the types of the passed values
+// so that all values of these types are redacted.
+// Example:
+//     wrap.RedactTypes(Password(""), (*Credentials)(nil))
+func RedactTypes(values ...interface{}) {
+	redactMtx.Lock()
+	defer redactMtx.Unlock()
+
+	for _, value := range values {
+		if value != nil {
+			redactedTypes[reflection.DerefType(reflect.TypeOf(value))] = struct{}{}
+		}
+	}
+}
+
+// RedactNames registers rules that redact arguments passed with Named
+// and struct fields with names containing one of the passed names,
+// ignoring case.
+// The default rules redact names containing "password", "passwd",
+// "secret", "token", "apikey", "api_key", and "iban".
+func RedactNames(names ...string) {
+	redactMtx.Lock()
+	defer redactMtx.Unlock()
+
+	for _, name := range names {
+		redactedNames = append(redactedNames, strings.ToLower(name))
+	}
+}
+
+// UnredactTypes removes the types of the passed values
+// registered with RedactTypes.
+func UnredactTypes(values ...interface{}) {
+	redactMtx.Lock()
+	defer redactMtx.Unlock()
+
+	for _, value := range values {
+		if value != nil {
+			delete(redactedTypes, reflection.DerefType(reflect.TypeOf(value)))
+		}
+	}
+}
+
+// UnredactNames removes the passed names
+// from the rules registered with RedactNames,
+// including the default rules.
+func UnredactNames(names ...string) {
+	redactMtx.Lock()
+	defer redactMtx.Unlock()
+
+	for _, name := range names {
+		name = strings.ToLower(name)
+		kept := redactedNames[:0:0]
+		for _, redacted := range redactedNames {
+			if redacted != name {
+				kept = append(kept, redacted)
+			}
+		}
+		redactedNames = kept
+	}
+}
+
+func isRedactedType(t reflect.Type) bool {
+	redactMtx.RLock()
+	defer redactMtx.RUnlock()
+
+	_, redacted := redactedTypes[reflection.DerefType(t)]
+	return redacted
+}
+
+func isRedactedName(name string) bool {
+	redactMtx.RLock()
+	defer redactMtx.RUnlock()
+
+	name = strings.ToLower(name)
+	for _, redacted := range redactedNames {
+		if strings.Contains(name, redacted) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	redactorType      = reflect.TypeOf((*Redactor)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isRedactor returns if values of type t, the values t points to,
+// or pointers to them implement Redactor.
+func isRedactor(t reflect.Type) bool {
+	return t.Implements(redactorType) || reflect.PtrTo(reflection.DerefType(t)).Implements(redactorType)
+}
+
+// redactorOf returns v as Redactor if v or a pointer
+// to an addressable copy of v implements Redactor.
+// Pointers must not be nil.
+func redactorOf(v reflect.Value) (Redactor, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	if r, ok := v.Interface().(Redactor); ok {
+		return r, true
+	}
+	if v.Kind() == reflect.Ptr || !reflect.PtrTo(v.Type()).Implements(redactorType) {
+		return nil, false
+	}
+	if v.CanAddr() {
+		return v.Addr().Interface().(Redactor), true
+	}
+	// Redact has a pointer receiver
+	c := reflect.New(v.Type())
+	c.Elem().Set(v)
+	return c.Interface().(Redactor), true
+}
+
+// isMarshaler returns if values of type t implement
+// json.Marshaler or encoding.TextMarshaler
+// which are used instead of the fields of the values.
+func isMarshaler(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+// redactedField returns if the field is redacted
+// because of its tag, type, or name.
+// Names are not checked for fields implementing Redactor
+// because their Redact method returns a safe representation.
+func redactedField(field reflect.StructField, jsonName string) bool {
+	if field.Tag.Get(RedactTag) == "redact" || isRedactedType(field.Type) {
+		return true
+	}
+	if isRedactor(field.Type) {
+		return false
+	}
+	return isRedactedName(field.Name) || isRedactedName(jsonName)
+}
+
+// needsRedaction returns if values of type t could contain
+// redacted values or values implementing Redactor.
+// Maps with string keys and interfaces always need redaction
+// because their keys and dynamic values are only known at runtime.
+// Values implementing json.Marshaler or encoding.TextMarshaler
+// are marshaled by their own methods and don't need redaction.
+func needsRedaction(t reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	if isRedactor(t) || isRedactedType(t) {
+		return true
+	}
+	if isMarshaler(t) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return needsRedaction(t.Elem(), visited)
+
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String || needsRedaction(t.Elem(), visited)
+
+	case reflect.Interface:
+		return true
+
+	case reflect.Struct:
+		for _, f := range jsonFields(t) {
+			if redactedField(f.field, f.name) || needsRedaction(f.field.Type, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// errRedactCycle is returned by marshalRedacted
+// for values that contain themselves.
+var errRedactCycle = errors.New("cyclic value can't be marshaled")
+
+// visitKey identifies a pointer, map, or slice
+// that is being marshaled by marshalRedacted.
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// marshalRedacted marshals v like json.Marshal
+// but with redacted struct fields, map values,
+// and values of redacted types replaced by Redacted
+// and values implementing Redactor replaced by
+// the result of their Redact method.
+// Map values are redacted if their keys match
+// one of the rules registered with RedactNames.
+func marshalRedacted(v reflect.Value) ([]byte, error) {
+	var b bytes.Buffer
+	err := writeRedacted(&b, v, make(map[visitKey]bool))
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func writeRedacted(b *bytes.Buffer, v reflect.Value, visiting map[visitKey]bool) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			b.WriteString("null")
+			return nil
+		}
+		if v.Kind() == reflect.Ptr {
+			key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+			if visiting[key] {
+				return errRedactCycle
+			}
+			visiting[key] = true
+			defer delete(visiting, key)
+		}
+		if r, ok := redactorOf(v); ok {
+			return writeJSON(b, r.Redact())
+		}
+		if isMarshaler(v.Type()) && !isRedactedType(v.Type()) {
+			return writeJSON(b, v.Interface())
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		b.WriteString("null")
+		return nil
+	}
+	if r, ok := redactorOf(v); ok {
+		return writeJSON(b, r.Redact())
+	}
+	if isRedactedType(v.Type()) {
+		b.WriteString(`"` + Redacted + `"`)
+		return nil
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(jsonMarshalerType) {
+		// Like json.Marshal, use MarshalJSON with pointer receiver
+		// for addressable values
+		return writeJSON(b, v.Addr().Interface())
+	}
+	if !needsRedaction(v.Type(), make(map[reflect.Type]bool)) {
+		return writeJSON(b, v.Interface())
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return writeRedactedFields(b, v, visiting)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				b.WriteString("null")
+				return nil
+			}
+			key := visitKey{ptr: v.Pointer(), typ: v.Type(), len: v.Len()}
+			if visiting[key] {
+				return errRedactCycle
+			}
+			visiting[key] = true
+			defer delete(visiting, key)
+		}
+		b.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeRedacted(b, v.Index(i), visiting); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+
+	case reflect.Map:
+		if v.IsNil() {
+			b.WriteString("null")
+			return nil
+		}
+		key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+		if visiting[key] {
+			return errRedactCycle
+		}
+		visiting[key] = true
+		defer delete(visiting, key)
+		return writeRedactedMap(b, v, visiting)
+
+	default:
+		return writeJSON(b, v.Interface())
+	}
+	return nil
+}
+
+func writeJSON(b *bytes.Buffer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b.Write(data)
+	return nil
+}
+
+// writeRedactedMap writes the map v with keys sorted
+// and formatted like json.Marshal does.
+func writeRedactedMap(b *bytes.Buffer, v reflect.Value, visiting map[visitKey]bool) error {
+	type entry struct {
+		name  string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		name, err := mapKeyName(iter.Key())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{name: name, value: iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+
+	b.WriteByte('{')
+	for i, e := range entries {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := writeJSON(b, e.name); err != nil {
+			return err
+		}
+		b.WriteByte(':')
+		if isRedactedName(e.name) {
+			b.WriteString(`"` + Redacted + `"`)
+			continue
+		}
+		if err := writeRedacted(b, e.value, visiting); err != nil {
+			return err
+		}
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+// mapKeyName returns the name of a map key in JSON.
+func mapKeyName(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if m, ok := key.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported map key type %s", key.Type())
+}
+
+// writeRedactedFields writes the struct v as JSON object
+// with the fields returned by jsonFields.
+func writeRedactedFields(b *bytes.Buffer, v reflect.Value, visiting map[visitKey]bool) error {
+	b.WriteByte('{')
+	comma := false
+	for _, f := range jsonFields(v.Type()) {
+		fieldValue, ok := fieldByIndex(v, f.index)
+		if !ok {
+			// Field of a nil embedded pointer
+			continue
+		}
+		if f.omitEmpty && isEmptyValue(fieldValue) {
+			continue
+		}
+		if comma {
+			b.WriteByte(',')
+		}
+		comma = true
+		if err := writeJSON(b, f.name); err != nil {
+			return err
+		}
+		b.WriteByte(':')
+		if redactedField(f.field, f.name) {
+			b.WriteString(`"` + Redacted + `"`)
+			continue
+		}
+		if !f.quoted || fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() {
+			if err := writeRedacted(b, fieldValue, visiting); err != nil {
+				return err
+			}
+			continue
+		}
+		// The string option encodes the JSON of the value as string
+		var value bytes.Buffer
+		if err := writeRedacted(&value, fieldValue, visiting); err != nil {
+			return err
+		}
+		if err := writeJSON(b, value.String()); err != nil {
+			return err
+		}
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+// fieldByIndex returns the field of the struct v
+// with the index sequence of a jsonField,
+// or false if an embedded struct pointer is nil.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
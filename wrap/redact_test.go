@@ -0,0 +1,248 @@
+package wrap
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testIBAN string
+
+func (iban testIBAN) Redact() string {
+	return "****" + string(iban[len(iban)-4:])
+}
+
+type testPassword string
+
+type testCredentials struct {
+	User     string
+	Password string `json:"pass"`
+	Secret   string `errors:"redact"`
+	Hidden   string `json:"-"`
+	Note     string `json:"note,omitempty"`
+}
+
+type testAccount struct {
+	testCredentials
+	Name  string
+	IBAN  testIBAN `json:"account"`
+	Login *testCredentials
+	Key   testPassword
+	Other string
+}
+
+type testPlain struct {
+	A string
+	B int `json:"b"`
+}
+
+type testNode struct {
+	Password string
+	Next     *testNode
+}
+
+type testPtrIBAN struct {
+	Number string
+}
+
+func (iban *testPtrIBAN) Redact() string {
+	return "****" + iban.Number[len(iban.Number)-4:]
+}
+
+type testPayment struct {
+	Account testPtrIBAN
+	Amount  int
+}
+
+type testHidden struct {
+	Token string
+	Any   interface{}
+}
+
+func (testHidden) MarshalJSON() ([]byte, error) {
+	return []byte(`{"visible":true}`), nil
+}
+
+type testX struct {
+	X string
+}
+
+type testTaggedX struct {
+	X string `json:"X"`
+}
+
+type testY struct {
+	Y string
+}
+
+type testOtherY struct {
+	Y string
+}
+
+type testJSONOptions struct {
+	Count  int       `json:",string"`
+	Name   string    `json:",string"`
+	Nested testPlain `json:",omitempty"`
+	Ptr    *int      `json:",string"`
+	Any    interface{}
+	testX
+	testTaggedX
+	testY
+	testOtherY
+}
+
+type testLogins struct {
+	Logins []testCredentials
+	Any    interface{}
+}
+
+func Test_formatArgRedaction(t *testing.T) {
+	RedactTypes(testPassword(""))
+	defer UnredactTypes(testPassword(""))
+
+	cyclic := &testNode{Password: "x"}
+	cyclic.Next = cyclic
+
+	tests := []struct {
+		arg      interface{}
+		expected string
+	}{
+		{testPlain{"a", 1}, `testPlain{"A":"a","b":1}`},
+		{&testPlain{"a", 1}, `testPlain{"A":"a","b":1}`},
+		{testIBAN("DE89370400440532013000"), `"****3000"`},
+		{testPassword("hunter2"), Redacted},
+		{Named("password", "hunter2"), Redacted},
+		{Named("userPassword", "hunter2"), Redacted},
+		{Named("user", "alice"), `"alice"`},
+		{Named("accessToken", nil), Redacted},
+		{Named("user", nil), `<nil>`},
+		{
+			testCredentials{User: "alice", Password: "p", Secret: "s", Hidden: "h"},
+			`testCredentials{"User":"alice","pass":"***","Secret":"***"}`,
+		},
+		{
+			&testAccount{
+				testCredentials: testCredentials{User: "alice", Note: "note"},
+				Name:            "Alice",
+				IBAN:            "DE89370400440532013000",
+				Login:           &testCredentials{User: "bob", Password: "p"},
+				Key:             "key",
+				Other:           "other",
+			},
+			`testAccount{"User":"alice","pass":"***","Secret":"***","note":"note","Name":"Alice","account":"****3000","Login":{"User":"bob","pass":"***","Secret":"***"},"Key":"***","Other":"other"}`,
+		},
+		{[]string{"a"}, `[]string{"a"}`},
+		{&[]int{1}, `[]int{1}`},
+		{map[int]int{1: 2}, `map[int]int{1:2}`},
+		{[]interface{}{"a", 1}, `[]interface {}{"a", 1}`},
+		{map[string]int{"a": 1}, `map[string]int{"a":1}`},
+		{[]testCredentials{{User: "a", Password: "hunter2"}}, `[]wrap.testCredentials{testCredentials{"User":"a","pass":"***","Secret":"***"}}`},
+		{[1]testPassword{"hunter2"}, `[1]wrap.testPassword{***}`},
+		{map[string]string{"password": "hunter2", "user": "a"}, `map[string]string{"password":***, "user":"a"}`},
+		{&map[string]interface{}{"b": testIBAN("DE89370400440532013000"), "a": []string{"x"}}, `map[string]interface {}{"a":[]string{"x"}, "b":"****3000"}`},
+		{[][]testPassword{nil}, `[][]wrap.testPassword{[]wrap.testPassword(nil)}`},
+		{testPtrIBAN{"DE1234567890"}, `"****7890"`},
+		{testPayment{Account: testPtrIBAN{"DE1234567890"}, Amount: 1}, `testPayment{"Account":"****7890","Amount":1}`},
+		{testHidden{Token: "hunter2", Any: 1}, `testHidden{"visible":true}`},
+		{
+			testJSONOptions{Count: 1, Name: "n", Any: map[string]int{"token": 1}, testTaggedX: testTaggedX{"tagged"}},
+			`testJSONOptions{"Count":"1","Name":"\"n\"","Nested":{"A":"","b":0},"Ptr":null,"Any":{"token":"***"},"X":"tagged"}`,
+		},
+		{
+			testLogins{
+				Logins: []testCredentials{{User: "a", Password: "hunter2"}},
+				Any:    map[string]string{"apiKey": "hunter2"},
+			},
+			`testLogins{"Logins":[{"User":"a","pass":"***","Secret":"***"}],"Any":{"apiKey":"***"}}`,
+		},
+		{
+			struct {
+				Logins []testCredentials
+				Any    interface{}
+			}{Any: testCredentials{Password: "hunter2"}},
+			`{"Logins":null,"Any":{"User":"","pass":"***","Secret":"***"}}`,
+		},
+		{cyclic, "testNodemarshaling error"},
+	}
+	for _, test := range tests {
+		result := formatArg(test.arg)
+		if result != test.expected {
+			t.Errorf("formatArg(%#v) `%s` != expected `%s`", test.arg, result, test.expected)
+		}
+	}
+}
+
+func Test_marshalRedactedLikeJSON(t *testing.T) {
+	one := 1
+	values := []interface{}{
+		testJSONOptions{Count: 1, Name: "n", testX: testX{"x"}, testTaggedX: testTaggedX{"tagged"}, testY: testY{"y"}},
+		&testJSONOptions{Ptr: &one, Any: testPlain{"a", 1}},
+	}
+	for _, value := range values {
+		expected, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := marshalRedacted(reflect.ValueOf(value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result) != string(expected) {
+			t.Errorf("marshalRedacted(%#v) `%s` != json.Marshal `%s`", value, result, expected)
+		}
+	}
+}
+
+func Test_RedactNames(t *testing.T) {
+	RedactNames("PIN")
+	defer UnredactNames("PIN")
+
+	result := FormatCallSignature("unlock", Named("cardPIN", 1234), Named("card", 1))
+	expected := `unlock(***, 1)`
+	if result != expected {
+		t.Errorf("result `%s` != expected `%s`", result, expected)
+	}
+}
+
+func redactedResultErrorFunc(password string) (err error) {
+	defer ResultError(&err, "redactedResultErrorFunc", Named("password", password))
+
+	return errors.New("TEST")
+}
+
+func Test_UnredactNames(t *testing.T) {
+	assert := func(name string, expected bool) {
+		t.Helper()
+		if isRedactedName(name) != expected {
+			t.Errorf("isRedactedName(%q) != %t", name, expected)
+		}
+	}
+
+	RedactNames("PIN")
+	assert("cardPIN", true)
+	UnredactNames("pin")
+	assert("cardPIN", false)
+	assert("password", true)
+}
+
+func Test_RedactionInErrors(t *testing.T) {
+	RedactTypes(testPassword(""))
+	defer UnredactTypes(testPassword(""))
+
+	creds := testCredentials{User: "alice", Password: "hunter2"}
+
+	err := Error(errors.New("TEST"), "login", creds, testPassword("hunter2"))
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("Error did not redact: %s", err)
+	}
+
+	err = redactedResultErrorFunc("hunter2")
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("ResultError did not redact: %s", err)
+	}
+	if !strings.Contains(err.Error(), "redactedResultErrorFunc(***)") {
+		t.Errorf("ResultError has unexpected message: %s", err)
+	}
+}
@@ -1,8 +1,6 @@
 package wrap
 
 import (
-	stderrors "errors"
-	"fmt"
 	"strings"
 	"time"
 
@@ -65,17 +63,7 @@ func RecoverAndLogPanic(log Logger, funcName string, funcArgs ...interface{}) {
 }
 
 func AsError(val interface{}) error {
-	switch x := val.(type) {
-	case nil:
-		return nil
-	case error:
-		return x
-	case string:
-		return stderrors.New(x)
-	case fmt.Stringer:
-		return stderrors.New(x.String())
-	}
-	return stderrors.New(fmt.Sprintf("%+v", val))
+	return errors.AsError(val)
 }
 
 func callSignature(funcName string, funcArgs []interface{}) string {